@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/zilliztech/milvus_benchmark/milvus_benchmark/benchmarker/pkg/benchmark"
+)
+
+type agentConfig struct {
+	Coordinator string
+	Listen      string
+}
+
+var globalAgentConfig agentConfig
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Run a share of a distributed benchmark",
+	Long:  "Register with a coordinator started by locust --agents, run the share of the workload it assigns and report the latencies back",
+	Run: func(cmd *cobra.Command, args []string) {
+		a := benchmark.NewAgent()
+		if err := a.Serve(globalAgentConfig.Listen, globalAgentConfig.Coordinator); err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func initAgent() {
+	rootCmd.AddCommand(agentCmd)
+
+	agentCmd.Flags().StringVar(&globalAgentConfig.Coordinator,
+		"coordinator", "", "Address of the coordinator to register with")
+	agentCmd.Flags().StringVar(&globalAgentConfig.Listen,
+		"listen", ":0", "Address to serve the coordinator on, it must be reachable from the coordinator")
+}
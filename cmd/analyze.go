@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/zilliztech/milvus_benchmark/milvus_benchmark/benchmarker/pkg/benchmark"
+)
+
+type analyzeConfig struct {
+	OutputFormat     string
+	Percentiles      []float64
+	PercentileMethod string
+}
+
+var globalAnalyzeConfig analyzeConfig
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze trace",
+	Short: "Compute the results of a run from its trace",
+	Long:  "Read a trace written by locust --traceOut, as .csv, .ndjson or .npy, and compute the latencies and throughput of the run with other percentiles if need be",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := benchmark.Config{
+			Percentiles:      globalAnalyzeConfig.Percentiles,
+			PercentileMethod: globalAnalyzeConfig.PercentileMethod,
+		}
+		records, err := benchmark.ReadTrace(args[0])
+		if err != nil {
+			fatal(err)
+		}
+		if len(records) == 0 {
+			fatal(errors.Errorf("trace %q has no requests", args[0]))
+		}
+		r, err := benchmark.AnalyzeTrace(cfg, records)
+		if err != nil {
+			fatal(err)
+		}
+		if err := benchmark.WriterSink(os.Stdout, globalAnalyzeConfig.OutputFormat).Write(r); err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func initAnalyze() {
+	rootCmd.AddCommand(analyzeCmd)
+
+	analyzeCmd.Flags().StringVarP(&globalAnalyzeConfig.OutputFormat,
+		"format", "f", "text", "Output format, one of [text, json, csv, markdown]")
+	analyzeCmd.Flags().Float64SliceVar(&globalAnalyzeConfig.Percentiles,
+		"percentiles", benchmark.DefaultPercentiles, "Latency percentiles to report, e.g. 50,99,99.9,99.99")
+	analyzeCmd.Flags().StringVar(&globalAnalyzeConfig.PercentileMethod,
+		"percentileMethod", benchmark.PercentileNearestRank, "Percentile estimation, one of [nearest-rank, linear]")
+}
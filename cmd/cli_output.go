@@ -5,6 +5,12 @@ import (
 	"os"
 )
 
+const (
+	exitFailure    = 1
+	exitRegression = 2
+	exitAssertion  = 3
+)
+
 const (
 	colorReset = "\033[0m"
 	colorRed   = "\033[1;31m"
@@ -13,7 +19,7 @@ const (
 
 func fatal(err error) {
 	fmt.Fprintf(os.Stderr, "%s%s%s\n", colorRed, err.Error(), colorReset)
-	os.Exit(1)
+	os.Exit(exitFailure)
 }
 
 func infof(msg string, format ...interface{}) {
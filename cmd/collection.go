@@ -1,35 +1,47 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	"github.com/xiaocai2333/milvus-sdk-go/v2/entity"
+	"github.com/zilliztech/milvus_benchmark/milvus_benchmark/benchmarker/pkg/benchmark"
 )
 
 var datasetCmd = &cobra.Command{
 	Use:   "locust",
 	Short: "Benchmark vectors from an existing collection",
-	Long:  "Specify an existing collection as a list of query vectors in a .json file or json str to parse the query vectors and then query them with the specified parallelism",
+	Long:  "Specify an existing collection and a list of query vectors in a .json, .hdf5, .npy, .fvecs or .bvecs file or json str to parse the query vectors and then query them with the specified parallelism",
 	Run: func(cmd *cobra.Command, args []string) {
 		cfg := globalConfig
 		cfg.Mode = "locust"
-		if err := json.NewDecoder(strings.NewReader(cfg.FormatParams)).Decode(&cfg.SearchParams); err != nil {
-			fatal(err)
+		// a replay sends the search params of its recording
+		if cfg.Replay == "" || cfg.FormatParams != "" {
+			if err := json.NewDecoder(strings.NewReader(cfg.FormatParams)).Decode(&cfg.SearchParams); err != nil {
+				fatal(err)
+			}
 		}
+		cfg.credentialsFromEnv()
 
 		if err := cfg.Validate(); err != nil {
 			fatal(err)
 		}
 
-		q, err := parseVectorsFromFile(cfg)
-		if err != nil {
-			fatal(err)
+		var src benchmark.QuerySource
+		if cfg.Replay == "" {
+			q, err := benchmark.ReadQueries(cfg.Config)
+			if err != nil {
+				fatal(err)
+			}
+			cfg.Nq = len(q)
+			src = q
 		}
-		cfg.Nq = len(q)
 
 		var w io.Writer
 		if cfg.OutputFile == "" {
@@ -43,69 +55,124 @@ var datasetCmd = &cobra.Command{
 			defer f.Close()
 			w = f
 		}
-		result := benchmarkDataset(cfg, q)
-		if cfg.OutputFormat == "json" {
-			result.WriteJsonTo(w)
-		} else if cfg.OutputFormat == "text" {
-			result.WriteTextTo(w)
+		_, err := benchmark.Run(context.Background(), cfg.Config, src, cfg.sinks(w)...)
+		if err != nil && !errors.Is(err, benchmark.ErrAssertionsFailed) {
+			fatal(err)
 		}
 
 		if cfg.OutputFile != "" {
 			infof("results successfully written to %q", cfg.OutputFile)
 		}
+		if cfg.HistoryFile != "" {
+			infof("results appended to history %q as case %q", cfg.HistoryFile, cfg.CaseName())
+		}
+		if cfg.Report != "" {
+			infof("report successfully written to %q", cfg.Report)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%sassertions failed%s\n", colorRed, colorReset)
+			os.Exit(exitAssertion)
+		}
 	},
 }
 
 func initDataset() {
 	rootCmd.AddCommand(datasetCmd)
 
-	// TODO: use parameters to distinguish file or json str
+	datasetCmd.PersistentFlags().StringVar(&globalConfig.Name,
+		"name", "", "Name of the case in the history, derived from the search params if empty")
+	datasetCmd.PersistentFlags().StringVar(&globalConfig.HistoryFile,
+		"history", "", "Append the results to this history file, e.g. "+benchmark.DefaultHistoryFile)
 	datasetCmd.PersistentFlags().StringVarP(&globalConfig.Origin,
-		"Origin", "u", "", "host for Milvus")
+		"Origin", "u", "", "host for Milvus, a comma separated list to spread the load over several proxies")
+	datasetCmd.PersistentFlags().StringVar(&globalConfig.Balance,
+		"balance", benchmark.BalanceRoundRobin, "Balancing over the hosts of -u, one of [round-robin, random, least-inflight]")
+	datasetCmd.PersistentFlags().StringVar(&globalConfig.BalanceScope,
+		"balanceScope", benchmark.BalancePerRequest, "Pick a host for every request or once per worker, one of [request, worker]")
+	datasetCmd.PersistentFlags().BoolVar(&globalConfig.Connection.TLS,
+		"tls", false, "Connect to Milvus over TLS, implied by the other tls flags")
+	datasetCmd.PersistentFlags().StringVar(&globalConfig.Connection.CAFile,
+		"tlsCA", "", "CA bundle to verify the server certificate with, the system one if empty")
+	datasetCmd.PersistentFlags().StringVar(&globalConfig.Connection.CertFile,
+		"tlsCert", "", "Client certificate for mutual TLS")
+	datasetCmd.PersistentFlags().StringVar(&globalConfig.Connection.KeyFile,
+		"tlsKey", "", "Key of the client certificate")
+	datasetCmd.PersistentFlags().StringVar(&globalConfig.Connection.ServerName,
+		"tlsServerName", "", "Server name to verify the certificate against instead of the host of --Origin")
+	datasetCmd.PersistentFlags().StringVar(&globalConfig.Connection.Username,
+		"username", "", "Milvus user")
+	datasetCmd.PersistentFlags().StringVar(&globalConfig.Connection.Password,
+		"password", "", "Password of the Milvus user, defaults to $MILVUS_PASSWORD")
+	datasetCmd.PersistentFlags().StringVar(&globalConfig.Connection.Token,
+		"token", "", "Token to authenticate with instead of a user, defaults to $MILVUS_TOKEN")
 	datasetCmd.PersistentFlags().StringVarP(&globalConfig.QueryFile,
-		"queryFile", "q", "", "Point to the queries file, (.json)")
+		"queryFile", "q", "", "Point to the queries file (.json, .hdf5, .npy, .fvecs, .bvecs) or a json str")
+	datasetCmd.PersistentFlags().StringVar(&globalConfig.QueryDataset,
+		"queryDataset", benchmark.DefaultQueryDataset, "Dataset path of the query vectors in a .hdf5 file")
+	datasetCmd.PersistentFlags().IntVar(&globalConfig.QueryOffset,
+		"queryOffset", 0, "Index of the first query vector to use")
+	datasetCmd.PersistentFlags().IntVar(&globalConfig.QueryRows,
+		"queryRows", 0, "Number of query vectors to use starting at queryOffset, 0 for all")
 	datasetCmd.PersistentFlags().StringVarP(&globalConfig.FormatParams,
 		"searchParams", "s", "", "params for operation")
+	datasetCmd.PersistentFlags().StringVar(&globalConfig.ConsistencyLevel,
+		"consistencyLevel", "", "Consistency of the searches unless set in the search params, one of [Strong, Bounded, Session, Eventually], Eventually if empty")
+	datasetCmd.PersistentFlags().Uint64Var(&globalConfig.GuaranteeTimestamp,
+		"guaranteeTimestamp", 0, "Guarantee timestamp of the searches instead of a consistency level")
 	datasetCmd.PersistentFlags().IntVarP(&globalConfig.Parallel,
 		"parallel", "p", 1, "Set the number of parallel threads which send queries")
 	datasetCmd.PersistentFlags().StringVarP(&globalConfig.OutputFormat,
-		"format", "f", "text", "Output format, one of [text, json]")
+		"format", "f", "text", "Output format, one of [text, json, csv, markdown]")
 	datasetCmd.PersistentFlags().IntVarP(&globalConfig.Total,
 		"total", "t", 1, "run times for test")
+	datasetCmd.PersistentFlags().Float64SliceVar(&globalConfig.Percentiles,
+		"percentiles", benchmark.DefaultPercentiles, "Latency percentiles to report, e.g. 50,99,99.9,99.99")
+	datasetCmd.PersistentFlags().StringVar(&globalConfig.PercentileMethod,
+		"percentileMethod", benchmark.PercentileNearestRank, "Percentile estimation, one of [nearest-rank, linear]")
+	datasetCmd.PersistentFlags().DurationVar(&globalConfig.ProgressInterval,
+		"progress", 5*time.Second, "Interval of the progress reported on stderr, 0 to disable")
+	datasetCmd.PersistentFlags().StringVar(&globalConfig.MetricsAddr,
+		"metricsAddr", "", "Serve live /metrics and /status on this address, e.g. :9100")
+	datasetCmd.PersistentFlags().IntVar(&globalConfig.Agents,
+		"agents", 0, "Split the run across this many agents instead of running it locally")
+	datasetCmd.PersistentFlags().StringVar(&globalConfig.CoordinatorAddr,
+		"coordinatorAddr", ":7100", "Address agents register on when --agents is set")
+	datasetCmd.PersistentFlags().DurationVar(&globalConfig.AgentTimeout,
+		"agentTimeout", time.Minute, "Time to wait for all agents to register, and for the results of the others once an agent answered")
+	datasetCmd.PersistentFlags().StringVar(&globalConfig.Report,
+		"report", "", "Write a self-contained html report to this file, e.g. out.html")
+	datasetCmd.PersistentFlags().DurationVar(&globalConfig.ReportInterval,
+		"reportInterval", time.Second, "Interval of the time series in the report")
+	datasetCmd.PersistentFlags().StringArrayVar(&globalConfig.Assert,
+		"assert", nil, "SLO assertion such as p99<50ms, qps>=2000 or error_rate<0.1%, may be repeated")
+	datasetCmd.PersistentFlags().Int64Var(&globalConfig.Seed,
+		"seed", 0, "Seed of the randomness of the run such as the random balancing, picked and recorded in the results if 0")
+	datasetCmd.PersistentFlags().BoolVar(&globalConfig.Calibrate,
+		"calibrate", false, "Run against an in-process no-op Milvus instead of -u to measure the overhead of the benchmarker per request")
+	datasetCmd.PersistentFlags().IntVar(&globalConfig.NICSpeed,
+		"nicSpeed", 0, "Speed of the client NIC in Mbit/s to flag runs it limits, read from the system if 0")
+	datasetCmd.PersistentFlags().BoolVar(&globalConfig.CheckResults,
+		"checkResults", false, "Check the hits of every search for anomalies, fail on them with --assert invalid_results<1")
+	datasetCmd.PersistentFlags().StringVar(&globalConfig.TraceOut,
+		"traceOut", "", "Write every request to this .csv, .ndjson or .npy file, to be read back with the analyze command")
+	datasetCmd.PersistentFlags().StringVar(&globalConfig.Capture,
+		"capture", "", "Record the requests with their vectors, params and timings to this file, to be sent again with --replay")
+	datasetCmd.PersistentFlags().StringVar(&globalConfig.Replay,
+		"replay", "", "Send the requests recorded with --capture instead of the query vectors with their search params, -q is not needed and -s cannot be used")
+	datasetCmd.PersistentFlags().Float64Var(&globalConfig.ReplaySpeed,
+		"replaySpeed", 1, "Pace of --replay relative to the recording, 2 for twice as fast, 0 for as fast as possible")
+	datasetCmd.PersistentFlags().IntVar(&globalConfig.Retry.MaxAttempts,
+		"retryMaxAttempts", 1, "Attempts of a search failing with a retryable code, 1 to not retry")
+	datasetCmd.PersistentFlags().DurationVar(&globalConfig.Retry.Backoff,
+		"retryBackoff", 100*time.Millisecond, "Wait before the first retry, doubled for every retry and jittered")
+	datasetCmd.PersistentFlags().DurationVar(&globalConfig.Retry.MaxBackoff,
+		"retryMaxBackoff", 5*time.Second, "Longest wait between two attempts")
+	datasetCmd.PersistentFlags().StringSliceVar(&globalConfig.Retry.Codes,
+		"retryCodes", benchmark.DefaultRetryCodes, "Retryable grpc status codes")
+	datasetCmd.PersistentFlags().StringVar(&globalConfig.ThinkTime,
+		"thinkTime", "", "Pause of every worker between its requests such as 100ms, uniform:50ms-150ms or exponential:100ms")
 
 	//datasetCmd.PersistentFlags().StringVarP(&globalConfig.OutputFile,
 	//	"output", "o", "", "Filename for an output file. If none provided, output to stdout only")
 
 }
-
-type Queries [][]float32
-
-func parseVectorsFromFile(cfg Config) (Queries, error) {
-	var q Queries
-	if strings.Contains(cfg.QueryFile, ".json") {
-		f, err := os.Open(cfg.QueryFile)
-		if err != nil {
-			return nil, err
-		}
-		defer f.Close()
-		if err := json.NewDecoder(f).Decode(&q); err != nil {
-			return nil, err
-		}
-	} else {
-		if err := json.NewDecoder(strings.NewReader(cfg.QueryFile)).Decode(&q); err != nil {
-			return nil, err
-		}
-	}
-	return q, nil
-}
-
-func benchmarkDataset(cfg Config, queries Queries) Results {
-	getQueryFunc := func() []entity.Vector {
-		vectors := make([]entity.Vector, 0)
-		for _, query := range queries {
-			vectors = append(vectors, entity.FloatVector(query))
-		}
-		return vectors
-	}
-	return benchmark(cfg, getQueryFunc)
-}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/zilliztech/milvus_benchmark/milvus_benchmark/benchmarker/pkg/benchmark"
+)
+
+var globalThresholds benchmark.Thresholds
+
+var compareCmd = &cobra.Command{
+	Use:   "compare baseline.json candidate.json",
+	Short: "Compare two json results and fail on regression",
+	Long:  "Diff the throughput, latencies and error rate of a candidate run against a baseline run, both written with -f json, and exit non-zero if any metric regressed beyond its threshold",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		diffs, err := benchmark.CompareFiles(args[0], args[1], globalThresholds)
+		if err != nil {
+			fatal(err)
+		}
+		benchmark.WriteDiffsTo(os.Stdout, diffs)
+		if benchmark.Regressed(diffs) {
+			fmt.Fprintf(os.Stderr, "%sregression detected%s\n", colorRed, colorReset)
+			os.Exit(exitRegression)
+		}
+		infof("no regression detected")
+	},
+}
+
+func initCompare() {
+	rootCmd.AddCommand(compareCmd)
+
+	compareCmd.Flags().Float64Var(&globalThresholds.QPSDrop,
+		"qpsThreshold", 5, "Max allowed qps drop, in percent of the baseline")
+	compareCmd.Flags().Float64Var(&globalThresholds.LatencyIncrease,
+		"latencyThreshold", 10, "Max allowed mean and percentile latency increase, in percent of the baseline")
+	compareCmd.Flags().Float64Var(&globalThresholds.ErrorRateIncrease,
+		"errorRateThreshold", 0.1, "Max allowed error rate increase, in percentage points")
+}
@@ -1,38 +1,35 @@
 package cmd
 
 import (
-	"time"
+	"io"
+	"os"
 
 	"github.com/pkg/errors"
+	"github.com/zilliztech/milvus_benchmark/milvus_benchmark/benchmarker/pkg/benchmark"
 )
 
+// Config is the configuration of a run given on the command line, on top of
+// the run itself it says where the results go.
 type Config struct {
-	SearchParams
-	Mode         string
-	Origin       string
-	Nq           int
-	Parallel     int
-	QueryFile    string
+	benchmark.Config
 	FormatParams string
-	Total        int
 	OutputFormat string
 	OutputFile   string
+	HistoryFile  string
+	Report       string
 }
 
-type SearchParams struct {
-	CollectionName string   `json:"collection_name"`
-	PartitionNames []string `json:"partition_names"`
-	FieldName      string   `json:"fieldName"`
-	IndexType      string   `json:"index_type"`
-	MetricType     string   `json:"metric_type"`
-	Params         struct {
-		Dim int `json:"dim"`
-		Ef  int `json:"ef"`
-	} `json:"params"`
-	Limit        int           `json:"limit"`
-	Expr         string        `json:"expr"`
-	OutputFields []string      `json:"output_fields"`
-	Timeout      time.Duration `json:"timeout"`
+// credentialsFromEnv fills in the secrets not given as flags from
+// $MILVUS_PASSWORD and $MILVUS_TOKEN, which keeps them out of the process
+// list.
+func (c *Config) credentialsFromEnv() {
+	conn := &c.Connection
+	if conn.Username != "" && conn.Password == "" {
+		conn.Password = os.Getenv("MILVUS_PASSWORD")
+	}
+	if conn.Username == "" && conn.Token == "" {
+		conn.Token = os.Getenv("MILVUS_TOKEN")
+	}
 }
 
 func (c Config) Validate() error {
@@ -51,22 +48,19 @@ func (c Config) Validate() error {
 	}
 }
 func (c Config) validateCommon() error {
-	if c.Origin == "" {
-		return errors.Errorf("origin must be set")
-	}
-	if c.CollectionName == "" {
-		return errors.Errorf("collectionName must be set")
+	if err := c.Config.Validate(); err != nil {
+		return err
 	}
 
 	switch c.OutputFormat {
-	case "text":
-		c.OutputFormat = "text"
-	case "json", "":
-		c.OutputFormat = "json"
+	case benchmark.FormatText, benchmark.FormatJSON, benchmark.FormatCSV, benchmark.FormatMarkdown:
 	default:
-		return errors.Errorf("unsupported output format %q, must be one of [text, json]",
+		return errors.Errorf("unsupported output format %q, must be one of [text, json, csv, markdown]",
 			c.OutputFormat)
 	}
+	if c.Report != "" && c.ReportInterval <= 0 {
+		return errors.Errorf("reportInterval must be positive")
+	}
 	return nil
 }
 
@@ -82,8 +76,21 @@ func (c Config) validateRandomVectors() error {
 }
 
 func (c Config) validateDataset() error {
-	if c.QueryFile == "" {
+	if c.QueryFile == "" && c.Replay == "" {
 		return errors.Errorf("query vectors must be provided by file or json str")
 	}
 	return nil
 }
+
+// sinks returns where the results of the run go, w is the output of the
+// formatted results.
+func (c Config) sinks(w io.Writer) []benchmark.Sink {
+	sinks := []benchmark.Sink{benchmark.WriterSink(w, c.OutputFormat)}
+	if c.HistoryFile != "" {
+		sinks = append(sinks, benchmark.HistorySink(c.HistoryFile))
+	}
+	if c.Report != "" {
+		sinks = append(sinks, benchmark.ReportSink(c.Report, c.HistoryFile))
+	}
+	return sinks
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigValidate(t *testing.T) {
+	cfg := Config{OutputFormat: "text"}
+	cfg.Mode = "locust"
+	cfg.Origin = "localhost:19530"
+	cfg.CollectionName = "test"
+	cfg.Parallel = 1
+	cfg.Total = 1
+	cfg.QueryFile = "[[1, 2]]"
+	assert.Nil(t, cfg.Validate())
+	assert.Equal(t, 1, len(cfg.sinks(io.Discard)))
+
+	cfg.HistoryFile = "history.jsonl"
+	cfg.Report = "out.html"
+	assert.Error(t, cfg.Validate())
+	cfg.ReportInterval = 1
+	assert.Nil(t, cfg.Validate())
+	assert.Equal(t, 3, len(cfg.sinks(io.Discard)))
+
+	invalid := cfg
+	invalid.OutputFormat = "yaml"
+	assert.Error(t, invalid.Validate())
+	invalid = cfg
+	invalid.QueryFile = ""
+	assert.Error(t, invalid.Validate())
+	invalid = cfg
+	invalid.Origin = ""
+	assert.Error(t, invalid.Validate())
+}
+
+func TestConfigCredentialsFromEnv(t *testing.T) {
+	t.Setenv("MILVUS_PASSWORD", "secret")
+	t.Setenv("MILVUS_TOKEN", "token")
+
+	var cfg Config
+	cfg.credentialsFromEnv()
+	assert.Equal(t, "token", cfg.Connection.Token)
+	assert.Equal(t, "", cfg.Connection.Password)
+
+	cfg = Config{}
+	cfg.Connection.Username = "root"
+	cfg.credentialsFromEnv()
+	assert.Equal(t, "secret", cfg.Connection.Password)
+	assert.Equal(t, "", cfg.Connection.Token)
+
+	cfg.Connection.Password = "flag"
+	cfg.credentialsFromEnv()
+	assert.Equal(t, "flag", cfg.Connection.Password)
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/zilliztech/milvus_benchmark/milvus_benchmark/benchmarker/pkg/benchmark"
+)
+
+type historyConfig struct {
+	File       string
+	Case       string
+	Collection string
+}
+
+var globalHistoryConfig historyConfig
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List recorded runs and show trends",
+	Long:  "List the runs appended to a history file by locust --history, or with --case show the qps and p99 trend of one case and flag outliers",
+	Run: func(cmd *cobra.Command, args []string) {
+		records, err := benchmark.ReadHistory(globalHistoryConfig.File)
+		if err != nil {
+			fatal(err)
+		}
+		records = benchmark.FilterHistory(records, globalHistoryConfig.Case, globalHistoryConfig.Collection)
+		if globalHistoryConfig.Case == "" {
+			benchmark.WriteHistoryTo(os.Stdout, records)
+		} else {
+			benchmark.WriteTrendTo(os.Stdout, records)
+		}
+	},
+}
+
+func initHistory() {
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().StringVar(&globalHistoryConfig.File,
+		"history", benchmark.DefaultHistoryFile, "History file to read")
+	historyCmd.Flags().StringVar(&globalHistoryConfig.Case,
+		"case", "", "Show the trend of the runs of this case")
+	historyCmd.Flags().StringVar(&globalHistoryConfig.Collection,
+		"collection", "", "Only show runs against this collection")
+}
@@ -11,6 +11,11 @@ var globalConfig Config
 
 func init() {
 	initDataset()
+	initCompare()
+	initAgent()
+	initHistory()
+	initServe()
+	initAnalyze()
 }
 
 var rootCmd = &cobra.Command{
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zilliztech/milvus_benchmark/milvus_benchmark/benchmarker/pkg/benchmark"
+)
+
+type serveConfig struct {
+	Listen string
+	Dir    string
+}
+
+var globalServeConfig serveConfig
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve benchmark runs over HTTP JSON-RPC",
+	Long:  "Keep connections to Milvus open and run the cases submitted with Benchmark.Run over HTTP JSON-RPC 2.0, streaming their progress and returning the results as json",
+	Run: func(cmd *cobra.Command, args []string) {
+		lis, err := listen(globalServeConfig.Listen)
+		if err != nil {
+			fatal(err)
+		}
+		defer lis.Close()
+		runner := benchmark.NewRunner()
+		defer runner.Close()
+
+		infof("serving json-rpc on %s", globalServeConfig.Listen)
+		if err := http.Serve(lis, benchmark.NewDaemon(runner, globalServeConfig.Dir)); err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func initServe() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&globalServeConfig.Listen,
+		"listen", "127.0.0.1:7200", "Address to serve on, unix:/path/to/socket for a unix socket")
+	serveCmd.Flags().StringVar(&globalServeConfig.Dir,
+		"dir", "", "Directory the query, trace, workload, history and report files of the cases are relative to, cases cannot name files if empty")
+}
+
+// listen listens on a tcp address or, with the unix: prefix, on a unix
+// socket. A socket left behind by a previous daemon is replaced.
+func listen(addr string) (net.Listener, error) {
+	if !strings.HasPrefix(addr, "unix:") {
+		return net.Listen("tcp", addr)
+	}
+	path := strings.TrimPrefix(addr, "unix:")
+	if stat, err := os.Stat(path); err == nil && stat.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	return net.Listen("unix", path)
+}
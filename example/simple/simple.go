@@ -2,20 +2,58 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"strconv"
 
 	"github.com/milvus-io/milvus-sdk-go/v2/client"
 	"github.com/milvus-io/milvus-sdk-go/v2/entity"
 	"github.com/zilliztech/milvus_benchmark/milvus_benchmark/benchmarker/internal/hdf5"
+	"github.com/zilliztech/milvus_benchmark/milvus_benchmark/benchmarker/internal/vecs"
 )
 
 const (
 	collName = "hdf5_test"
 )
 
+var (
+	dataFile  = flag.String("data", "../../benchmark-data/glove-25-angular.hdf5", "hdf5 dataset, or .fvecs/.bvecs base vectors; .ivecs hold ids and are rejected")
+	queryFile = flag.String("query", "", ".fvecs/.bvecs query vectors, required when -data is a vecs file")
+)
+
+// vecsDataset tells whether -data is a file of base vectors rather than an
+// hdf5 dataset, only .fvecs and .bvecs hold vectors.
+func vecsDataset() bool {
+	switch vecs.TypeOf(*dataFile) {
+	case vecs.FVECS, vecs.BVECS:
+		return true
+	}
+	return false
+}
+
 func main() {
+	flag.Parse()
 	ctx := context.Background()
+	dim := 25
+	if vecs.TypeOf(*dataFile) == vecs.IVECS {
+		log.Fatal("-data must hold vectors, .ivecs files hold ids such as the ground truth")
+	}
+	if vecsDataset() {
+		if *queryFile == "" {
+			log.Fatal("-query must be set for vecs datasets")
+		}
+		obj, err := vecs.Open(*dataFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		_, dim, _, err = obj.MetaInfo()
+		obj.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 	fmt.Println("start connecting to Milvus")
 	client, err := client.NewGrpcClient(ctx, "localhost:19530")
 	if err != nil {
@@ -46,7 +84,7 @@ func main() {
 				Name:     "vec",
 				DataType: entity.FieldTypeFloatVector,
 				TypeParams: map[string]string{
-					entity.TYPE_PARAM_DIM: "25",
+					entity.TYPE_PARAM_DIM: strconv.Itoa(dim),
 				},
 			},
 		},
@@ -67,7 +105,11 @@ func main() {
 	}
 	fmt.Println("insert data...")
 
-	batchInsert(ctx, client)
+	if vecsDataset() {
+		batchInsertVecs(ctx, client, dim)
+	} else {
+		batchInsert(ctx, client)
+	}
 
 	if err := client.Flush(ctx, collName, false); err != nil {
 		log.Fatalf("failed to flush data, err: %v", err)
@@ -82,7 +124,7 @@ func main() {
 }
 
 func batchInsert(ctx context.Context, client client.Client) {
-	h5obj, err := hdf5.Open("../../benchmark-data/glove-25-angular.hdf5")
+	h5obj, err := hdf5.Open(*dataFile)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -119,8 +161,50 @@ func batchInsert(ctx context.Context, client client.Client) {
 
 }
 
-func dosearch(ctx context.Context, client client.Client) {
-	h5obj, err := hdf5.Open("../../benchmark-data/glove-25-angular.hdf5")
+// batchInsertVecs streams the base vectors batch by batch, so that sets
+// like BIGANN never have to fit in memory at once.
+func batchInsertVecs(ctx context.Context, client client.Client, dim int) {
+	obj, err := vecs.Open(*dataFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer obj.Close()
+	batch, total := 10000, 0
+	for {
+		embeddingList, err := obj.ReadFloat32Range(total, batch)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			log.Fatal(err)
+		}
+		idList := make([]int64, 0, len(embeddingList))
+		for i := range embeddingList {
+			idList = append(idList, int64(total+i))
+		}
+		idColData := entity.NewColumnInt64("int64", idList)
+		embeddingColData := entity.NewColumnFloatVector("vec", dim, embeddingList)
+		if _, err := client.Insert(ctx, collName, "", idColData, embeddingColData); err != nil {
+			log.Fatal(err)
+		}
+		total += len(embeddingList)
+		fmt.Printf("inserted %d rows, total: %d\n", len(embeddingList), total)
+	}
+}
+
+func readTestVectors() [][]float32 {
+	if vecsDataset() {
+		obj, err := vecs.Open(*queryFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer obj.Close()
+		testList, err := obj.ReadFloat32Matrix()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return testList
+	}
+	h5obj, err := hdf5.Open(*dataFile)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -133,6 +217,11 @@ func dosearch(ctx context.Context, client client.Client) {
 	if err != nil {
 		log.Fatal(err)
 	}
+	return testList
+}
+
+func dosearch(ctx context.Context, client client.Client) {
+	testList := readTestVectors()
 	sp, err := entity.NewIndexFlatSearchParam(10)
 	if err != nil {
 		log.Fatal(err)
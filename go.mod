@@ -5,8 +5,13 @@ go 1.17
 require (
 	github.com/milvus-io/milvus-sdk-go/v2 v2.0.0
 	github.com/pkg/errors v0.9.1
+	github.com/sbinet/npyio v0.6.0
 	github.com/spf13/cobra v1.4.0
+	github.com/stretchr/testify v1.8.0
 	github.com/xiaocai2333/milvus-sdk-go/v2 v2.0.11
+	gonum.org/v1/hdf5 v0.0.0-20210714002203-8c5d23bc6946
+	google.golang.org/grpc v1.31.0
+	google.golang.org/protobuf v1.23.0
 )
 
 require (
@@ -15,16 +20,11 @@ require (
 	github.com/golang/protobuf v1.4.3 // indirect
 	github.com/inconshreveable/mousetrap v1.0.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/sbinet/npyio v0.6.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
-	github.com/stretchr/testify v1.8.0 // indirect
 	golang.org/x/net v0.0.0-20190620200207-3b0461eec859 // indirect
 	golang.org/x/sys v0.0.0-20210304124612-50617c2ba197 // indirect
 	golang.org/x/text v0.3.5 // indirect
 	gonum.org/v1/gonum v0.9.3 // indirect
-	gonum.org/v1/hdf5 v0.0.0-20210714002203-8c5d23bc6946 // indirect
 	google.golang.org/genproto v0.0.0-20190819201941-24fa4b261c55 // indirect
-	google.golang.org/grpc v1.31.0 // indirect
-	google.golang.org/protobuf v1.23.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
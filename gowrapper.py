@@ -1,7 +1,17 @@
 from pymilvus import (
     Collection,Index
 )
-import subprocess, json, os
+import subprocess, json, os, socket
+import http.client
+
+class _UnixHTTPConnection(http.client.HTTPConnection):
+    def __init__(self, path):
+        super().__init__("localhost")
+        self._path = path
+
+    def connect(self):
+        self.sock = socket.socket(socket.AF_UNIX, socket.SOCK_STREAM)
+        self.sock.connect(self._path)
 
 class BenchMarker(Collection):
 
@@ -10,6 +20,8 @@ class BenchMarker(Collection):
         super().__init__(name, schema, using, shards_num, **kwargs)
         self.indexes_set = dict()
         self._gopkg = dir_path
+        self._daemon = None
+        self._request_id = 0
     def set_parallel(self, parallel):
         self._parallel = parallel
     
@@ -19,6 +31,34 @@ class BenchMarker(Collection):
     def set_total(self, total):
         self._total = total
 
+    def set_daemon(self, address):
+        """Submit the cases to a `benchmarker serve` daemon instead of running
+        a process per case, address is host:port or unix:/path/to/socket."""
+        self._daemon = address
+
+    def _call_daemon(self, method, params, on_progress=None):
+        if self._daemon.startswith("unix:"):
+            conn = _UnixHTTPConnection(self._daemon[len("unix:"):])
+        else:
+            conn = http.client.HTTPConnection(self._daemon)
+        self._request_id += 1
+        body = json.dumps({"jsonrpc": "2.0", "id": self._request_id, "method": method, "params": params})
+        conn.request("POST", "/", body, {"Content-Type": "application/json"})
+        resp = conn.getresponse()
+        try:
+            for line in resp:
+                msg = json.loads(line)
+                if msg.get("method") == "Benchmark.Progress":
+                    if on_progress is not None:
+                        on_progress(msg["params"])
+                    continue
+                if "error" in msg:
+                    raise RuntimeError(msg["error"]["message"])
+                return msg["result"]
+        finally:
+            conn.close()
+        raise RuntimeError("daemon closed the connection without a result")
+
     def create_index(self, field_name, index_params={}, timeout=None, **kwargs) -> Index:
         self.indexes_set[field_name] = index_params
         return super().create_index(field_name, index_params, timeout, **kwargs)
@@ -42,8 +82,19 @@ class BenchMarker(Collection):
             "expr": expr,
             "output_fields": output_fields,
             "timeout": timeout,
+            "consistency_level": kwargs.get("consistency_level"),
+            "guarantee_timestamp": kwargs.get("guarantee_timestamp"),
         }
         conn = super()._get_connection()
+        if self._daemon is not None:
+            return self._call_daemon("Benchmark.Run", {
+                "origin": conn.server_address,
+                "search_params": query_json,
+                "queries": data,
+                "parallel": self._parallel,
+                "total": self._total,
+                "progress_interval": kwargs.get("progress_interval", 5),
+            }, kwargs.get("on_progress", lambda p: print("{}/{} qps: {:.1f}".format(p["completed"], p["total"], p["qps"]))))
         process = subprocess.Popen(
             cwd=self._gopkg,
             args=['go', 'run', '.', 'locust', '-u', conn.server_address, '-q', json.dumps(data, indent=2),
@@ -1,6 +1,7 @@
 package numpy
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -96,3 +97,29 @@ func TestNumpy_float(t *testing.T) {
 	_, err = obj.ReadInt64Matrix()
 	assert.Error(t, err)
 }
+
+func TestMatrixWriter(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "out.npy")
+	w, err := Create(fname, INT64, 3)
+	assert.Nil(t, err)
+	for i := int64(0); i < 1000; i++ {
+		assert.Nil(t, w.WriteRow([]int64{i, i * 2, -i}))
+	}
+	assert.Error(t, w.WriteRow([]int64{1, 2}))
+	assert.Error(t, w.WriteRow([]float64{1, 2, 3}))
+	assert.Nil(t, w.Close())
+
+	obj, err := Open(fname)
+	assert.Nil(t, err)
+	defer obj.Close()
+	dims, dataType, err := obj.MetaInfo()
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1000, 3}, dims)
+	assert.Equal(t, INT64, dataType)
+	data, err := obj.ReadInt64Matrix()
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{999, 1998, -999}, data[999])
+
+	_, err = Create(fname, UNKNOWN, 3)
+	assert.Error(t, err)
+}
@@ -0,0 +1,95 @@
+package numpy
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// headerLen is the length of the header written by MatrixWriter, large
+// enough for any shape so that Close can rewrite it in place.
+const headerLen = 128
+
+var itemKinds = map[DataType]reflect.Kind{
+	Bool: reflect.Bool, UINT8: reflect.Uint8, UINT16: reflect.Uint16,
+	UINT32: reflect.Uint32, UINT64: reflect.Uint64, INT8: reflect.Int8,
+	INT16: reflect.Int16, INT32: reflect.Int32, INT64: reflect.Int64,
+	FLOAT32: reflect.Float32, FLOAT64: reflect.Float64,
+}
+
+// MatrixWriter streams the rows of a 2-d matrix into a .npy file, the
+// number of rows is only known and written by Close.
+type MatrixWriter struct {
+	fhandle  *os.File
+	writer   *bufio.Writer
+	dataType DataType
+	cols     int
+	rows     int
+}
+
+func Create(fname string, dataType DataType, cols int) (*MatrixWriter, error) {
+	if _, ok := itemKinds[dataType]; !ok {
+		return nil, fmt.Errorf("unsupported data type %q", dataType)
+	}
+	if cols <= 0 {
+		return nil, fmt.Errorf("invalid dimensions")
+	}
+	f, err := os.Create(fname)
+	if err != nil {
+		return nil, err
+	}
+	w := &MatrixWriter{
+		fhandle:  f,
+		writer:   bufio.NewWriter(f),
+		dataType: dataType,
+		cols:     cols,
+	}
+	if _, err := w.writer.Write(w.header()); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// header returns the version 1.0 header of the matrix written so far.
+func (w *MatrixWriter) header() []byte {
+	dict := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%d, %d), }",
+		w.dataType, w.rows, w.cols)
+	// magic, version and the length of the dict take 10 bytes
+	dict += strings.Repeat(" ", headerLen-10-len(dict)-1) + "\n"
+	out := append([]byte("\x93NUMPY\x01\x00"), 0, 0)
+	binary.LittleEndian.PutUint16(out[8:], uint16(len(dict)))
+	return append(out, dict...)
+}
+
+// WriteRow appends a row, a slice of cols values of the data type of the
+// matrix such as []int64 for INT64.
+func (w *MatrixWriter) WriteRow(row interface{}) error {
+	rv := reflect.ValueOf(row)
+	if rv.Kind() != reflect.Slice || rv.Len() != w.cols {
+		return fmt.Errorf("invalid row, expected a slice of %d values", w.cols)
+	}
+	if rv.Type().Elem().Kind() != itemKinds[w.dataType] {
+		return fmt.Errorf("type mismatch")
+	}
+	if err := binary.Write(w.writer, binary.LittleEndian, row); err != nil {
+		return err
+	}
+	w.rows++
+	return nil
+}
+
+// Close flushes the rows and writes the final shape into the header.
+func (w *MatrixWriter) Close() error {
+	err := w.writer.Flush()
+	if err == nil {
+		_, err = w.fhandle.WriteAt(w.header(), 0)
+	}
+	if closeErr := w.fhandle.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
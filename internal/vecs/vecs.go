@@ -0,0 +1,255 @@
+package vecs
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// DataType is the element type of a TEXMEX vecs file, named after its extension.
+type DataType string
+
+const (
+	FVECS   DataType = "fvecs"
+	BVECS   DataType = "bvecs"
+	IVECS   DataType = "ivecs"
+	UNKNOWN DataType = "!vecs"
+)
+
+func (t DataType) elemSize() int {
+	switch t {
+	case FVECS, IVECS:
+		return 4
+	case BVECS:
+		return 1
+	}
+	return 0
+}
+
+// TypeOf returns the vecs data type of fname according to its extension.
+func TypeOf(fname string) DataType {
+	switch DataType(filepath.Ext(fname)) {
+	case "." + FVECS:
+		return FVECS
+	case "." + BVECS:
+		return BVECS
+	case "." + IVECS:
+		return IVECS
+	}
+	return UNKNOWN
+}
+
+// VecsObject streams rows out of a .fvecs, .bvecs or .ivecs file. Every row
+// is stored as a little-endian int32 dimension followed by dim elements.
+type VecsObject struct {
+	nread   int
+	rows    int
+	dim     int
+	dtype   DataType
+	opened  bool
+	fhandle *os.File
+	reader  *bufio.Reader
+}
+
+func Open(fname string) (*VecsObject, error) {
+	dtype := TypeOf(fname)
+	if dtype == UNKNOWN {
+		return nil, fmt.Errorf("unknown vecs file extension: %q", filepath.Ext(fname))
+	}
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	obj := &VecsObject{
+		dtype:   dtype,
+		opened:  true,
+		fhandle: f,
+		reader:  bufio.NewReader(f),
+	}
+	if err := obj.readHeader(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (h *VecsObject) readHeader() error {
+	stat, err := h.fhandle.Stat()
+	if err != nil {
+		return err
+	}
+	if stat.Size() == 0 {
+		return nil
+	}
+	var dim int32
+	if err := binary.Read(h.reader, binary.LittleEndian, &dim); err != nil {
+		return err
+	}
+	if dim <= 0 {
+		return fmt.Errorf("invalid dimension: %d", dim)
+	}
+	rowSize := int64(4 + int(dim)*h.dtype.elemSize())
+	if stat.Size()%rowSize != 0 {
+		return fmt.Errorf("invalid file size %d for dimension %d", stat.Size(), dim)
+	}
+	h.dim = int(dim)
+	h.rows = int(stat.Size() / rowSize)
+	return h.Seek(0)
+}
+
+func (h *VecsObject) Close() error {
+	h.opened = false
+	return h.fhandle.Close()
+}
+
+func (h *VecsObject) MetaInfo() (rows int, dim int, dataType DataType, err error) {
+	if !h.opened {
+		return 0, 0, UNKNOWN, fmt.Errorf("object closed")
+	}
+	return h.rows, h.dim, h.dtype, nil
+}
+
+// Seek moves the read position to the given row.
+func (h *VecsObject) Seek(row int) error {
+	if !h.opened {
+		return fmt.Errorf("object closed")
+	}
+	if row < 0 || row > h.rows {
+		return fmt.Errorf("row %d out of range [0, %d]", row, h.rows)
+	}
+	offset := int64(row) * int64(4+h.dim*h.dtype.elemSize())
+	if _, err := h.fhandle.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	h.reader.Reset(h.fhandle)
+	h.nread = row
+	return nil
+}
+
+// readRows reads up to n rows into a buffer allocated by newBuf, which must
+// return a slice of rows of the element type matching the file.
+func (h *VecsObject) readRows(n int, newBuf func(rows int) interface{}) (interface{}, error) {
+	if !h.opened {
+		return nil, fmt.Errorf("object closed")
+	}
+	if h.nread >= h.rows {
+		return nil, io.EOF
+	}
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid row count: %d", n)
+	}
+	if n > h.rows-h.nread {
+		n = h.rows - h.nread
+	}
+	buf := newBuf(n)
+	for i := 0; i < n; i++ {
+		var dim int32
+		if err := binary.Read(h.reader, binary.LittleEndian, &dim); err != nil {
+			return nil, err
+		}
+		if int(dim) != h.dim {
+			return nil, fmt.Errorf("dimension mismatch at row %d: %d != %d", h.nread, dim, h.dim)
+		}
+		var err error
+		switch b := buf.(type) {
+		case [][]float32:
+			b[i] = make([]float32, h.dim)
+			err = binary.Read(h.reader, binary.LittleEndian, b[i])
+		case [][]uint8:
+			b[i] = make([]uint8, h.dim)
+			_, err = io.ReadFull(h.reader, b[i])
+		case [][]int32:
+			b[i] = make([]int32, h.dim)
+			err = binary.Read(h.reader, binary.LittleEndian, b[i])
+		}
+		if err != nil {
+			return nil, err
+		}
+		h.nread++
+	}
+	return buf, nil
+}
+
+// ReadFloat32Rows reads the next n rows of a .fvecs file. Fewer rows are
+// returned at the end of the file, and io.EOF once it is exhausted.
+func (h *VecsObject) ReadFloat32Rows(n int) ([][]float32, error) {
+	if h.dtype != FVECS {
+		return nil, fmt.Errorf("type mismatch")
+	}
+	buf, err := h.readRows(n, func(rows int) interface{} { return make([][]float32, rows) })
+	if err != nil {
+		return nil, err
+	}
+	return buf.([][]float32), nil
+}
+
+// ReadUInt8Rows reads the next n rows of a .bvecs file.
+func (h *VecsObject) ReadUInt8Rows(n int) ([][]uint8, error) {
+	if h.dtype != BVECS {
+		return nil, fmt.Errorf("type mismatch")
+	}
+	buf, err := h.readRows(n, func(rows int) interface{} { return make([][]uint8, rows) })
+	if err != nil {
+		return nil, err
+	}
+	return buf.([][]uint8), nil
+}
+
+// ReadInt32Rows reads the next n rows of a .ivecs file.
+func (h *VecsObject) ReadInt32Rows(n int) ([][]int32, error) {
+	if h.dtype != IVECS {
+		return nil, fmt.Errorf("type mismatch")
+	}
+	buf, err := h.readRows(n, func(rows int) interface{} { return make([][]int32, rows) })
+	if err != nil {
+		return nil, err
+	}
+	return buf.([][]int32), nil
+}
+
+// ReadFloat32Range reads rows [start, start+n) as float32 vectors. Both
+// .fvecs and .bvecs files are accepted, bytes are widened to float32.
+func (h *VecsObject) ReadFloat32Range(start, n int) ([][]float32, error) {
+	if err := h.Seek(start); err != nil {
+		return nil, err
+	}
+	switch h.dtype {
+	case FVECS:
+		return h.ReadFloat32Rows(n)
+	case BVECS:
+		data, err := h.ReadUInt8Rows(n)
+		if err != nil {
+			return nil, err
+		}
+		ret := make([][]float32, len(data))
+		for i, row := range data {
+			ret[i] = make([]float32, len(row))
+			for j, v := range row {
+				ret[i][j] = float32(v)
+			}
+		}
+		return ret, nil
+	}
+	return nil, fmt.Errorf("type mismatch")
+}
+
+func (h *VecsObject) ReadFloat32Matrix() ([][]float32, error) {
+	return h.ReadFloat32Range(0, h.rows)
+}
+
+func (h *VecsObject) ReadUInt8Matrix() ([][]uint8, error) {
+	if err := h.Seek(0); err != nil {
+		return nil, err
+	}
+	return h.ReadUInt8Rows(h.rows)
+}
+
+func (h *VecsObject) ReadInt32Matrix() ([][]int32, error) {
+	if err := h.Seek(0); err != nil {
+		return nil, err
+	}
+	return h.ReadInt32Rows(h.rows)
+}
@@ -0,0 +1,134 @@
+package vecs
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeVecs writes rows*dim sequential values in vecs layout, each element
+// encoded by put into its elemSize bytes.
+func writeVecs(t *testing.T, fname string, rows, dim, elemSize int, put func(b []byte, v int)) {
+	rowSize := 4 + dim*elemSize
+	buf := make([]byte, rows*rowSize)
+	for i := 0; i < rows; i++ {
+		row := buf[i*rowSize:]
+		binary.LittleEndian.PutUint32(row, uint32(dim))
+		for j := 0; j < dim; j++ {
+			put(row[4+j*elemSize:], i*dim+j)
+		}
+	}
+	assert.Nil(t, os.WriteFile(fname, buf, 0644))
+}
+
+func TestVecs_fvecs(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "test.fvecs")
+	writeVecs(t, fname, 100, 8, 4, func(b []byte, v int) {
+		binary.LittleEndian.PutUint32(b, math.Float32bits(float32(v)))
+	})
+
+	obj, err := Open(fname)
+	assert.Nil(t, err)
+	defer obj.Close()
+
+	rows, dim, dtype, err := obj.MetaInfo()
+	assert.Nil(t, err)
+	assert.Equal(t, 100, rows)
+	assert.Equal(t, 8, dim)
+	assert.Equal(t, FVECS, dtype)
+
+	data, err := obj.ReadFloat32Matrix()
+	assert.Nil(t, err)
+	assert.Equal(t, 100, len(data))
+	assert.Equal(t, float32(99*8+7), data[99][7])
+
+	data, err = obj.ReadFloat32Range(10, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(data))
+	assert.Equal(t, float32(80), data[0][0])
+
+	_, err = obj.ReadInt32Rows(1)
+	assert.Error(t, err)
+}
+
+func TestVecs_streaming(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "test.ivecs")
+	writeVecs(t, fname, 25, 4, 4, func(b []byte, v int) {
+		binary.LittleEndian.PutUint32(b, uint32(v))
+	})
+
+	obj, err := Open(fname)
+	assert.Nil(t, err)
+	defer obj.Close()
+
+	var all [][]int32
+	for {
+		batch, err := obj.ReadInt32Rows(10)
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		all = append(all, batch...)
+	}
+	assert.Equal(t, 25, len(all))
+	assert.Equal(t, []int32{96, 97, 98, 99}, all[24])
+}
+
+func TestVecs_bvecs(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "test.bvecs")
+	writeVecs(t, fname, 10, 16, 1, func(b []byte, v int) {
+		b[0] = uint8(v)
+	})
+
+	obj, err := Open(fname)
+	assert.Nil(t, err)
+	defer obj.Close()
+
+	data, err := obj.ReadUInt8Matrix()
+	assert.Nil(t, err)
+	assert.Equal(t, uint8(17), data[1][1])
+
+	floats, err := obj.ReadFloat32Range(9, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(floats))
+	assert.Equal(t, float32(144), floats[0][0])
+}
+
+func TestVecs_invalid(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := Open(filepath.Join(dir, "test.npy"))
+	assert.Error(t, err)
+
+	truncated := filepath.Join(dir, "truncated.fvecs")
+	writeVecs(t, truncated, 2, 4, 4, func(b []byte, v int) {})
+	f, err := os.OpenFile(truncated, os.O_WRONLY|os.O_APPEND, 0644)
+	assert.Nil(t, err)
+	_, err = f.Write([]byte{1, 2, 3})
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+	_, err = Open(truncated)
+	assert.Error(t, err)
+
+	mismatch := filepath.Join(dir, "mismatch.ivecs")
+	writeVecs(t, mismatch, 2, 4, 4, func(b []byte, v int) {})
+	f, err = os.OpenFile(mismatch, os.O_WRONLY, 0644)
+	assert.Nil(t, err)
+	_, err = f.WriteAt([]byte{3, 0, 0, 0}, 20)
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+	obj, err := Open(mismatch)
+	assert.Nil(t, err)
+	defer obj.Close()
+	_, err = obj.ReadInt32Matrix()
+	assert.Error(t, err)
+
+	assert.Nil(t, obj.Close())
+	_, _, _, err = obj.MetaInfo()
+	assert.Error(t, err)
+}
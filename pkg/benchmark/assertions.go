@@ -0,0 +1,161 @@
+package benchmark
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// assertion is a single SLO check such as "p99<50ms", "qps>=2000",
+// "error_rate<0.1%" or "invalid_results<1". Threshold is kept in the unit
+// the metric is measured in: nanoseconds for latencies, a fraction for the
+// error rate.
+type assertion struct {
+	Raw       string
+	Metric    string
+	Op        string
+	Threshold float64
+}
+
+type AssertionResult struct {
+	Assertion string
+	Actual    string
+	Passed    bool
+}
+
+// longer operators first so that "<=" is not parsed as "<"
+var assertionOps = []string{"<=", ">=", "<", ">"}
+
+func parseAssertion(s string) (assertion, error) {
+	raw := strings.TrimSpace(s)
+	for _, op := range assertionOps {
+		idx := strings.Index(raw, op)
+		if idx < 0 {
+			continue
+		}
+		a := assertion{
+			Raw:    raw,
+			Metric: strings.TrimSpace(raw[:idx]),
+			Op:     op,
+		}
+		value := strings.TrimSpace(raw[idx+len(op):])
+		var err error
+		switch {
+		case a.isLatency():
+			var d time.Duration
+			d, err = time.ParseDuration(value)
+			a.Threshold = float64(d)
+		case a.Metric == "error_rate":
+			if strings.HasSuffix(value, "%") {
+				a.Threshold, err = strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+				a.Threshold /= 100
+			} else {
+				a.Threshold, err = strconv.ParseFloat(value, 64)
+			}
+		case a.Metric == "qps", a.Metric == "invalid_results":
+			a.Threshold, err = strconv.ParseFloat(value, 64)
+		default:
+			return a, errors.Errorf("unsupported metric %q in assertion %q, must be one of [min, mean, max, stddev, pN, qps, error_rate, invalid_results]",
+				a.Metric, raw)
+		}
+		if err != nil {
+			return a, errors.Wrapf(err, "invalid value in assertion %q", raw)
+		}
+		return a, nil
+	}
+	return assertion{}, errors.Errorf("invalid assertion %q, expected <metric><op><value> with op one of %v",
+		raw, assertionOps)
+}
+
+func (a assertion) isLatency() bool {
+	switch a.Metric {
+	case "min", "mean", "max", "stddev":
+		return true
+	}
+	if !strings.HasPrefix(a.Metric, "p") {
+		return false
+	}
+	_, err := strconv.ParseFloat(a.Metric[1:], 64)
+	return err == nil
+}
+
+// actual looks the asserted metric up in r and returns it in the unit of
+// Threshold along with a human readable form.
+func (a assertion) actual(r Results) (float64, string, error) {
+	switch a.Metric {
+	case "min":
+		return float64(r.Min), fmt.Sprint(r.Min), nil
+	case "mean":
+		return float64(r.Mean), fmt.Sprint(r.Mean), nil
+	case "max":
+		return float64(r.Max), fmt.Sprint(r.Max), nil
+	case "stddev":
+		return float64(r.StdDev), fmt.Sprint(r.StdDev), nil
+	case "qps":
+		return r.QueriesPerSecond, fmt.Sprintf("%f", r.QueriesPerSecond), nil
+	case "error_rate":
+		rate := r.errorRate()
+		return rate, fmt.Sprintf("%.4f%%", rate*100), nil
+	case "invalid_results":
+		if r.ResultCheck == nil {
+			return 0, "", errors.Errorf("results are not checked in this run")
+		}
+		return float64(r.ResultCheck.Invalid), fmt.Sprint(r.ResultCheck.Invalid), nil
+	}
+	want, _ := strconv.ParseFloat(a.Metric[1:], 64)
+	for i, percentile := range r.PercentilesLabels {
+		if percentile == want {
+			return float64(r.Percentiles[i]), fmt.Sprint(r.Percentiles[i]), nil
+		}
+	}
+	return 0, "", errors.Errorf("percentile %s is not measured in this run", a.Metric)
+}
+
+func (a assertion) evaluate(r Results) AssertionResult {
+	out := AssertionResult{Assertion: a.Raw}
+	v, formatted, err := a.actual(r)
+	if err != nil {
+		out.Actual = err.Error()
+		return out
+	}
+	out.Actual = formatted
+	switch a.Op {
+	case "<":
+		out.Passed = v < a.Threshold
+	case "<=":
+		out.Passed = v <= a.Threshold
+	case ">":
+		out.Passed = v > a.Threshold
+	case ">=":
+		out.Passed = v >= a.Threshold
+	}
+	return out
+}
+
+func parseAssertions(specs []string) ([]assertion, error) {
+	out := make([]assertion, 0, len(specs))
+	for _, s := range specs {
+		a, err := parseAssertion(s)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+// checkAssertions evaluates every assertion against r, records the outcomes
+// in r.Assertions and reports whether all of them passed.
+func checkAssertions(r *Results, assertions []assertion) bool {
+	passed := true
+	r.Assertions = make([]AssertionResult, 0, len(assertions))
+	for _, a := range assertions {
+		res := a.evaluate(*r)
+		passed = passed && res.Passed
+		r.Assertions = append(r.Assertions, res)
+	}
+	return passed
+}
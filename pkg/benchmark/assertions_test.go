@@ -0,0 +1,47 @@
+package benchmark
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAssertion(t *testing.T) {
+	a, err := parseAssertion("p99<50ms")
+	assert.Nil(t, err)
+	assert.Equal(t, assertion{Raw: "p99<50ms", Metric: "p99", Op: "<", Threshold: float64(50 * time.Millisecond)}, a)
+
+	a, err = parseAssertion(" qps >= 2000 ")
+	assert.Nil(t, err)
+	assert.Equal(t, ">=", a.Op)
+	assert.Equal(t, float64(2000), a.Threshold)
+
+	a, err = parseAssertion("error_rate<0.1%")
+	assert.Nil(t, err)
+	assert.InDelta(t, 0.001, a.Threshold, 1e-12)
+
+	for _, invalid := range []string{"p99", "p99<fast", "latency<1ms", "qps=100", "recall>=0.9"} {
+		_, err = parseAssertion(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestCheckAssertions(t *testing.T) {
+	r := testResults()
+	assertions, err := parseAssertions([]string{
+		"p99<=100ms", "mean<50ms", "qps>=100", "error_rate<5%", "p99.9<1s",
+	})
+	assert.Nil(t, err)
+
+	assert.False(t, checkAssertions(&r, assertions))
+	passed := make([]bool, 0, len(r.Assertions))
+	for _, a := range r.Assertions {
+		passed = append(passed, a.Passed)
+	}
+	assert.Equal(t, []bool{true, false, true, true, false}, passed)
+	assert.Equal(t, "1.9608%", r.Assertions[3].Actual)
+
+	assert.True(t, checkAssertions(&r, assertions[:1]))
+	assert.Equal(t, 1, len(r.Assertions))
+}
@@ -0,0 +1,737 @@
+package benchmark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	milvusClient "github.com/xiaocai2333/milvus-sdk-go/v2/client"
+	"github.com/xiaocai2333/milvus-sdk-go/v2/entity"
+)
+
+func benchmark(ctx context.Context, cfg Config, eps []endpoint, src QuerySource) (Results, error) {
+	e, err := execute(ctx, cfg, eps, src)
+	if err != nil {
+		return Results{}, err
+	}
+	out := analyze(cfg, e.rec.latencies(), e.took)
+	out.Run = newRunInfo(cfg, e.server, e.start, e.start.Add(e.took))
+	out.Series = e.rec.series(e.start, e.took, cfg.ReportInterval)
+	out.Endpoints = e.rec.endpointStats(cfg, cfg.origins(), e.took)
+	out.ResultCheck = e.check
+	out.Payload = e.payload
+	out.ClientLoad = e.load
+	out.Retries = e.retries.stats(cfg, out.PercentilesLabels)
+	out.ThinkTime = e.think
+	return out, nil
+}
+
+// execution is what a run leaves behind before it is analyzed.
+type execution struct {
+	rec     *recorder
+	server  ServerInfo
+	start   time.Time
+	took    time.Duration
+	check   *ResultCheck
+	payload *PayloadStats
+	load    *ClientLoad
+	retries *retryRecord
+	think   *ThinkTimeStats
+}
+
+// searchRequest is a request of a run, seq is its index in the run. Worker
+// and at, the offset from the start of the run it was sent at, are only set
+// for the requests of a workload.
+type searchRequest struct {
+	seq     int
+	vectors []entity.Vector
+	worker  int
+	at      time.Duration
+}
+
+// execute sends cfg.Total searches with cfg.Parallel workers. The workers are
+// started at cfg.StartAt if it is set, so that several processes can start
+// in sync. The searches are spread over eps as cfg.Balance says and retried
+// as cfg.Retry says. The first search failing with an error that is not
+// retryable stops the run, the requests sent until then are still written
+// to cfg.TraceOut and cfg.Capture. If src is a workload, each of its
+// workers sends its requests no earlier than their offsets in the recording
+// scaled by cfg.ReplaySpeed, or as fast as possible if it is 0. Otherwise
+// each worker pauses for cfg.ThinkTime between its requests.
+func execute(ctx context.Context, cfg Config, eps []endpoint, src QuerySource) (execution, error) {
+	searchParams, err := newSearchParams(cfg.Params.Ef, cfg.IndexType)
+	if err != nil {
+		return execution{}, err
+	}
+	guaranteeTimestamp, err := cfg.guaranteeTimestamp()
+	if err != nil {
+		return execution{}, err
+	}
+	think, err := parseThinkTime(cfg.ThinkTime)
+	if err != nil {
+		return execution{}, err
+	}
+	opts, err := dialOptions(cfg.Connection)
+	if err != nil {
+		return execution{}, err
+	}
+	server := ServerInfo{Version: calibrationOrigin}
+	if !cfg.Calibrate {
+		server = describeServer(ctx, eps[0], cfg, opts)
+	}
+	rec := newRecorder(cfg.Total)
+
+	replay, _ := src.(*workload)
+	var queues [][]searchRequest
+	if replay != nil {
+		queues = replay.queues()
+	} else {
+		queues = make([][]searchRequest, cfg.Parallel)
+		for i := 0; i < cfg.Total; i++ {
+			worker := i % cfg.Parallel
+			queues[worker] = append(queues[worker], searchRequest{seq: i, vectors: src.Next()})
+		}
+	}
+	if wait := time.Until(cfg.StartAt); wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return execution{}, ctx.Err()
+		}
+	}
+	start := time.Now()
+	progress := startProgress(rec, cfg.Total, cfg.ProgressInterval, cfg.OnProgress)
+	defer progress.Stop()
+	metrics, err := startMetricsServer(cfg.MetricsAddr, cfg, rec, start)
+	if err != nil {
+		return execution{}, err
+	}
+	defer metrics.Stop()
+
+	trace, err := startTrace(cfg.TraceOut)
+	if err != nil {
+		return execution{}, err
+	}
+	capture, err := startCapture(cfg.Capture, cfg.SearchParams)
+	if err != nil {
+		trace.Close()
+		return execution{}, err
+	}
+	checker := startResultCheck(cfg)
+	// started after the setup that may fail, which would leak its goroutine
+	load := startLoadSampler()
+
+	payload := &payloadCounter{}
+	ctx, cancel := context.WithCancel(withPayloadCounter(ctx, payload))
+	defer cancel()
+	var once sync.Once
+	var failure error
+	balance := newBalancer(cfg.Balance, len(eps))
+	// every worker adds up its own pauses
+	pauses := make([]ThinkTimeStats, len(queues))
+	wg := &sync.WaitGroup{}
+	for w, queue := range queues {
+		wg.Add(1)
+		// the pauses draw from a stream of their own, so that a retry or
+		// another balance does not shift them
+		go func(w int, queue []searchRequest, rng, thinkRng *rand.Rand) {
+			defer wg.Done()
+			pinned := -1
+			if cfg.BalanceScope == BalancePerWorker {
+				pinned = balance.pick(rng)
+				defer balance.done(pinned)
+			}
+			for k, req := range queue {
+				if k > 0 && think.enabled() {
+					d := think.draw(thinkRng)
+					if !sleepUntil(ctx, time.Now().Add(d)) {
+						return
+					}
+					pauses[w].add(d)
+				}
+				if replay != nil && cfg.ReplaySpeed > 0 {
+					at := time.Duration(float64(req.at) / cfg.ReplaySpeed)
+					if !sleepUntil(ctx, start.Add(at)) {
+						return
+					}
+				}
+				if ctx.Err() != nil {
+					return
+				}
+				rec.begin(opSearch)
+				before := time.Now()
+				var results []milvusClient.SearchResult
+				var err error
+				var first time.Duration
+				var attempt int
+				for attempt = 1; ; attempt++ {
+					// a retry may go to another endpoint than the failed attempt
+					i := pinned
+					if i < 0 {
+						i = balance.pick(rng)
+					}
+					sent := time.Now()
+					results, err = eps[i].client.Search(ctx, cfg.CollectionName, cfg.PartitionNames, cfg.Expr, cfg.OutputFields,
+						req.vectors, cfg.FieldName, entity.MetricType(cfg.MetricType), cfg.Limit, searchParams, guaranteeTimestamp)
+					latency := time.Since(sent)
+					if attempt == 1 {
+						first = latency
+					}
+					rec.recordAttempt(eps[i].origin, latency, err)
+					if pinned < 0 {
+						balance.done(i)
+					}
+					if attempt >= cfg.Retry.MaxAttempts || !cfg.Retry.retryable(err) ||
+						!sleepUntil(ctx, time.Now().Add(cfg.Retry.backoff(attempt, rng))) {
+						break
+					}
+					rec.retry(opSearch, err)
+				}
+				took := time.Since(before)
+				rec.record(opSearch, took, err)
+				if cfg.Retry.enabled() {
+					rec.recordAttempts(first, attempt, err)
+				}
+				trace.add(newTraceRecord(before, w, opSearch, req, took, err))
+				capture.add(req, w, before.Sub(start))
+				if err == nil {
+					checker.add(req.seq, req.vectors, results)
+				}
+				// a request that exhausted its retries is a failure of
+				// the run, not the end of it
+				if err != nil && !cfg.Retry.retryable(err) {
+					once.Do(func() {
+						failure = err
+						cancel()
+					})
+					return
+				}
+			}
+		}(w, queue, newStream(cfg.Seed, "worker", w), newStream(cfg.Seed, "think", w))
+	}
+
+	wg.Wait()
+	took := time.Since(start)
+	clientLoad := load.Stop()
+	check := checker.Close()
+	if err := trace.Close(); err != nil {
+		capture.Close()
+		return execution{}, err
+	}
+	if err := capture.Close(); err != nil {
+		return execution{}, err
+	}
+	if failure != nil {
+		return execution{}, failure
+	}
+	if err := ctx.Err(); err != nil {
+		return execution{}, err
+	}
+	var thinkStats *ThinkTimeStats
+	if think.enabled() {
+		thinkStats = &ThinkTimeStats{}
+		for i := range pauses {
+			thinkStats.merge(&pauses[i])
+		}
+		thinkStats.ThinkTime = cfg.ThinkTime
+	}
+	speed := cfg.NICSpeed
+	if speed == 0 {
+		speed = nicSpeed()
+	}
+	return execution{
+		rec:     rec,
+		server:  server,
+		start:   start,
+		took:    took,
+		check:   check,
+		payload: newPayloadStats(payload, took, speed),
+		load:    clientLoad,
+		retries: rec.retryRecord(),
+		think:   thinkStats,
+	}, nil
+}
+
+// sleepUntil waits until t, it returns false if ctx is done first.
+func sleepUntil(ctx context.Context, t time.Time) bool {
+	wait := time.Until(t)
+	if wait <= 0 {
+		return true
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func newSearchParams(p int, indexType string) (entity.SearchParam, error) {
+	var searchParams entity.SearchParam
+	var err error
+	switch indexType {
+	case "HNSW":
+		searchParams, err = entity.NewIndexHNSWSearchParam(p)
+	case "IVF_FLAT":
+		searchParams, err = entity.NewIndexIvfFlatSearchParam(p)
+	case "IVF_SQ8":
+		searchParams, err = entity.NewIndexIvfSQ8SearchParam(p)
+	default:
+		return nil, errors.Errorf("illegal search params, unsupported index type %q", indexType)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "illegal search params for %s", indexType)
+	}
+	return searchParams, nil
+}
+
+// DefaultPercentiles are the latency percentiles reported unless
+// Config.Percentiles is set.
+var DefaultPercentiles = []float64{50, 90, 95, 98, 99}
+
+// Percentile estimation methods of Config.PercentileMethod.
+const (
+	PercentileNearestRank = "nearest-rank"
+	PercentileLinear      = "linear"
+)
+
+// Results are the statistics of a run.
+type Results struct {
+	Min               time.Duration
+	Max               time.Duration
+	Mean              time.Duration
+	StdDev            time.Duration
+	Took              time.Duration
+	QueriesPerSecond  float64
+	Percentiles       []time.Duration
+	PercentilesLabels []float64
+	Total             int
+	Successful        int
+	Failed            int
+	Parallelization   int
+	Assertions        []AssertionResult
+	Run               RunInfo
+	// Histogram is the latency distribution of the successful requests.
+	Histogram []HistogramBin
+	// Series is the throughput and latency per interval of the run, it is
+	// not available for distributed runs.
+	Series []IntervalStats
+	// Endpoints breaks a run across several endpoints down by endpoint, it
+	// is not available for distributed runs either.
+	Endpoints []EndpointStats
+	// ResultCheck holds the anomalies found in the search results, it is
+	// only set if Config.CheckResults is.
+	ResultCheck *ResultCheck
+	// Payload is the size of the searches and their results on the wire.
+	Payload *PayloadStats
+	// ClientLoad is how busy the benchmarker was, it tells whether the
+	// latencies are those of Milvus or of an overloaded client.
+	ClientLoad *ClientLoad
+	// Retries is how many requests were retried, it is only set if
+	// Config.Retry is.
+	Retries *RetryStats
+	// ThinkTime is how long the workers paused between their requests, it
+	// is only set if Config.ThinkTime is.
+	ThinkTime *ThinkTimeStats
+}
+
+func (r Results) errorRate() float64 {
+	if r.Total == 0 {
+		return 0
+	}
+	return float64(r.Failed) / float64(r.Total)
+}
+
+func analyze(cfg Config, times []time.Duration, total time.Duration) Results {
+	percentiles := cfg.Percentiles
+	if len(percentiles) == 0 {
+		percentiles = DefaultPercentiles
+	}
+	out := Results{
+		Min:               math.MaxInt64,
+		PercentilesLabels: percentiles,
+	}
+
+	var sum time.Duration
+
+	for _, t := range times {
+		if t < out.Min {
+			out.Min = t
+		}
+
+		if t > out.Max {
+			out.Max = t
+		}
+
+		out.Successful++
+		sum += t
+	}
+	out.Total = cfg.Total
+	out.Failed = cfg.Total - out.Successful
+	out.Parallelization = cfg.Parallel
+	out.Took = total
+	out.QueriesPerSecond = float64(len(times)) / float64(float64(total)/float64(time.Second))
+	out.Percentiles = make([]time.Duration, len(percentiles))
+	if len(times) == 0 {
+		out.Min = 0
+		return out
+	}
+	out.Mean = sum / time.Duration(len(times))
+
+	var variance float64
+	for _, t := range times {
+		d := float64(t - out.Mean)
+		variance += d * d
+	}
+	out.StdDev = time.Duration(math.Sqrt(variance / float64(len(times))))
+
+	sort.Slice(times, func(a, b int) bool {
+		return times[a] < times[b]
+	})
+	for i, percentile := range percentiles {
+		if cfg.PercentileMethod == PercentileLinear {
+			out.Percentiles[i] = linearPercentile(times, percentile)
+		} else {
+			out.Percentiles[i] = nearestRankPercentile(times, percentile)
+		}
+	}
+	out.Histogram = latencyDistribution(times, histogramBins)
+
+	return out
+}
+
+// nearestRankPercentile returns the smallest value such that at least
+// percentile% of the sorted times are less than or equal to it.
+func nearestRankPercentile(sorted []time.Duration, percentile float64) time.Duration {
+	rank := int(math.Ceil(percentile / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// linearPercentile interpolates between the two closest ranks, the same as
+// numpy.percentile with its default linear method.
+func linearPercentile(sorted []time.Duration, percentile float64) time.Duration {
+	h := percentile / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(h))
+	if lo >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lo] + time.Duration((h-float64(lo))*float64(sorted[lo+1]-sorted[lo]))
+}
+
+func percentileLabel(percentile float64) string {
+	return "p" + strconv.FormatFloat(percentile, 'f', -1, 64)
+}
+
+func (r Results) WriteTextTo(w io.Writer) (int64, error) {
+	b := strings.Builder{}
+
+	for i, percentile := range r.PercentilesLabels {
+		b.WriteString(
+			fmt.Sprintf("%s: %s\n", percentileLabel(percentile), r.Percentiles[i]),
+		)
+	}
+	if len(r.Assertions) > 0 {
+		b.WriteString("Assertions\n")
+	}
+	for _, a := range r.Assertions {
+		result := "pass"
+		if !a.Passed {
+			result = "FAIL"
+		}
+		b.WriteString(fmt.Sprintf("%s: %s (actual %s)\n", result, a.Assertion, a.Actual))
+	}
+	if r.ClientLoad != nil {
+		b.WriteString("Client\n" + r.ClientLoad.String())
+	}
+	if r.Payload != nil {
+		b.WriteString("Payload\n" + r.Payload.String())
+	}
+	if r.Retries != nil {
+		b.WriteString("Retries\n" + r.Retries.String(r.PercentilesLabels))
+	}
+	if r.ThinkTime != nil {
+		b.WriteString("Think time\n" + r.ThinkTime.String())
+	}
+	if c := r.ResultCheck; c != nil {
+		b.WriteString(fmt.Sprintf("Result check\nChecked: %d\nInvalid: %d\n", c.Checked, c.Invalid))
+		for _, kind := range anomalyKinds {
+			s, ok := c.Anomalies[kind]
+			if !ok {
+				continue
+			}
+			b.WriteString(fmt.Sprintf("%s: %d", kind, s.Count))
+			for _, e := range s.Examples {
+				b.WriteString(fmt.Sprintf(", request %d query %d: %s", e.Request, e.Query, e.Detail))
+			}
+			b.WriteString("\n")
+		}
+	}
+	if len(r.Endpoints) > 0 {
+		b.WriteString("Endpoints\n")
+	}
+	for _, e := range r.Endpoints {
+		b.WriteString(fmt.Sprintf("%s: successful %d, failed %d, qps %f, mean %s, max %s",
+			e.Origin, e.Successful, e.Failed, e.QueriesPerSecond, e.Mean, e.Max))
+		for i, percentile := range r.PercentilesLabels {
+			b.WriteString(fmt.Sprintf(", %s %s", percentileLabel(percentile), e.Percentiles[i]))
+		}
+		b.WriteString("\n")
+	}
+	n, err := w.Write([]byte(fmt.Sprintf(
+		"Results\nSuccessful: %d\nFailed: %d\nMin: %s\nMean: %s\nMax: %s\nStdDev: %s\nTook: %s\nQPS: %f\n%s",
+		r.Successful, r.Failed, r.Min, r.Mean, r.Max, r.StdDev, r.Took, r.QueriesPerSecond, b.String())))
+	return int64(n), err
+}
+
+type resultsJSON struct {
+	Metadata           resultsJSONMetadata    `json:"metadata"`
+	Latencies          map[string]int64       `json:"latencies"`
+	LatenciesFormatted map[string]string      `json:"latencies_formatted"`
+	Throughput         resultsJSONThroughput  `json:"throughput"`
+	Assertions         []resultsJSONAssertion `json:"assertions,omitempty"`
+	Endpoints          []resultsJSONEndpoint  `json:"endpoints,omitempty"`
+	Payload            *resultsJSONPayload    `json:"payload,omitempty"`
+	ClientLoad         *resultsJSONClientLoad `json:"client_load,omitempty"`
+	// Retries is only present when the run had a retry policy.
+	Retries *resultsJSONRetries `json:"retries,omitempty"`
+	// ThinkTime is only present when the workers paused between requests.
+	ThinkTime *resultsJSONThinkTime `json:"think_time,omitempty"`
+	// ResultCheck is only present when the search results were checked.
+	ResultCheck *resultsJSONResultCheck `json:"result_check,omitempty"`
+}
+
+// resultsJSONPayload holds the sizes in bytes and the rates in bytes per
+// second.
+type resultsJSONPayload struct {
+	Calls                  int     `json:"calls"`
+	RequestBytes           int64   `json:"request_bytes"`
+	ResponseBytes          int64   `json:"response_bytes"`
+	AvgRequestBytes        float64 `json:"avg_request_bytes"`
+	AvgResponseBytes       float64 `json:"avg_response_bytes"`
+	RequestBytesPerSecond  float64 `json:"request_bytes_per_second"`
+	ResponseBytesPerSecond float64 `json:"response_bytes_per_second"`
+	NICSpeed               int     `json:"nic_speed_mbps,omitempty"`
+	NICBound               bool    `json:"nic_bound"`
+}
+
+// resultsJSONClientLoad holds the cpu usage in cores and the scheduler
+// latencies in nanoseconds.
+type resultsJSONClientLoad struct {
+	Cores    int      `json:"cores"`
+	CPUUsage *float64 `json:"cpu_usage,omitempty"`
+	PeakCPU  *float64 `json:"peak_cpu,omitempty"`
+	SchedP50 int64    `json:"sched_latency_p50"`
+	SchedP99 int64    `json:"sched_latency_p99"`
+	SchedMax int64    `json:"sched_latency_max"`
+	CPUBound bool     `json:"cpu_bound"`
+}
+
+// resultsJSONRetries holds the latencies of the first attempts in
+// nanoseconds.
+type resultsJSONRetries struct {
+	MaxAttempts           int              `json:"max_attempts"`
+	Attempts              []int            `json:"attempts"`
+	Retried               int              `json:"retried"`
+	Retries               int              `json:"retries"`
+	Recovered             int              `json:"recovered"`
+	FinalFailures         int              `json:"final_failures"`
+	FirstAttemptLatencies map[string]int64 `json:"first_attempt_latencies"`
+}
+
+// resultsJSONThinkTime holds the pauses in nanoseconds.
+type resultsJSONThinkTime struct {
+	ThinkTime string `json:"think_time"`
+	Pauses    int    `json:"pauses"`
+	Mean      int64  `json:"mean"`
+	Total     int64  `json:"total"`
+}
+
+type resultsJSONResultCheck struct {
+	Checked   int                           `json:"checked"`
+	Invalid   int                           `json:"invalid"`
+	Anomalies map[string]resultsJSONAnomaly `json:"anomalies"`
+}
+
+type resultsJSONAnomaly struct {
+	Count    int                         `json:"count"`
+	Examples []resultsJSONAnomalyExample `json:"examples"`
+}
+
+type resultsJSONAnomalyExample struct {
+	Request int       `json:"request"`
+	Query   int       `json:"query"`
+	Detail  string    `json:"detail"`
+	Vector  []float32 `json:"vector,omitempty"`
+}
+
+type resultsJSONEndpoint struct {
+	Origin     string           `json:"origin"`
+	Successful int              `json:"successful"`
+	Failed     int              `json:"failed"`
+	QPS        float64          `json:"qps"`
+	Latencies  map[string]int64 `json:"latencies"`
+}
+
+type resultsJSONAssertion struct {
+	Assertion string `json:"assertion"`
+	Actual    string `json:"actual"`
+	Passed    bool   `json:"passed"`
+}
+
+type resultsJSONMetadata struct {
+	Successful      int             `json:"successful"`
+	Failed          int             `json:"failed"`
+	Total           int             `json:"total"`
+	Parallelization int             `json:"parallelization"`
+	Took            int64           `json:"took"`
+	TookFormatted   string          `json:"took_formatted"`
+	Run             *resultsJSONRun `json:"run,omitempty"`
+}
+
+type resultsJSONThroughput struct {
+	QPS float64 `json:"qps"`
+}
+
+func (r Results) toJSON() resultsJSON {
+	obj := resultsJSON{
+		Metadata: resultsJSONMetadata{
+			Successful:      r.Successful,
+			Total:           r.Total,
+			Failed:          r.Failed,
+			Parallelization: r.Parallelization,
+			Took:            int64(r.Took),
+			TookFormatted:   fmt.Sprint(r.Took),
+			Run:             r.Run.toJSON(),
+		},
+		Latencies: map[string]int64{
+			"mean":   int64(r.Mean),
+			"min":    int64(r.Min),
+			"max":    int64(r.Max),
+			"stddev": int64(r.StdDev),
+		},
+		LatenciesFormatted: map[string]string{
+			"mean":   fmt.Sprint(r.Mean),
+			"min":    fmt.Sprint(r.Min),
+			"max":    fmt.Sprint(r.Max),
+			"stddev": fmt.Sprint(r.StdDev),
+		},
+		Throughput: resultsJSONThroughput{
+			QPS: r.QueriesPerSecond,
+		},
+	}
+
+	for i, percentile := range r.PercentilesLabels {
+		obj.Latencies[percentileLabel(percentile)] = int64(r.Percentiles[i])
+		obj.LatenciesFormatted[percentileLabel(percentile)] = fmt.Sprint(r.Percentiles[i])
+	}
+
+	for _, a := range r.Assertions {
+		obj.Assertions = append(obj.Assertions, resultsJSONAssertion(a))
+	}
+	if p := r.Payload; p != nil {
+		obj.Payload = &resultsJSONPayload{
+			Calls:                  p.Calls,
+			RequestBytes:           p.RequestBytes,
+			ResponseBytes:          p.ResponseBytes,
+			AvgRequestBytes:        p.AvgRequestBytes(),
+			AvgResponseBytes:       p.AvgResponseBytes(),
+			RequestBytesPerSecond:  p.RequestBytesPerSecond,
+			ResponseBytesPerSecond: p.ResponseBytesPerSecond,
+			NICSpeed:               p.NICSpeed,
+			NICBound:               p.NICBound,
+		}
+	}
+	if l := r.ClientLoad; l != nil {
+		obj.ClientLoad = &resultsJSONClientLoad{
+			Cores:    l.Cores,
+			SchedP50: int64(l.SchedP50),
+			SchedP99: int64(l.SchedP99),
+			SchedMax: int64(l.SchedMax),
+			CPUBound: l.CPUBound,
+		}
+		if l.CPUMeasured {
+			cpuUsage, peakCPU := l.CPUUsage, l.PeakCPU
+			obj.ClientLoad.CPUUsage, obj.ClientLoad.PeakCPU = &cpuUsage, &peakCPU
+		}
+	}
+	if s := r.Retries; s != nil {
+		obj.Retries = &resultsJSONRetries{
+			MaxAttempts:   s.MaxAttempts,
+			Attempts:      s.Attempts,
+			Retried:       s.Retried,
+			Retries:       s.Retries,
+			Recovered:     s.Recovered,
+			FinalFailures: s.FinalFailures,
+			FirstAttemptLatencies: map[string]int64{
+				"mean": int64(s.FirstAttemptMean),
+				"max":  int64(s.FirstAttemptMax),
+			},
+		}
+		for i, percentile := range r.PercentilesLabels {
+			obj.Retries.FirstAttemptLatencies[percentileLabel(percentile)] = int64(s.FirstAttemptPercentiles[i])
+		}
+	}
+	if s := r.ThinkTime; s != nil {
+		obj.ThinkTime = &resultsJSONThinkTime{
+			ThinkTime: s.ThinkTime,
+			Pauses:    s.Pauses,
+			Mean:      int64(s.Mean()),
+			Total:     int64(s.Total),
+		}
+	}
+	if c := r.ResultCheck; c != nil {
+		obj.ResultCheck = &resultsJSONResultCheck{
+			Checked:   c.Checked,
+			Invalid:   c.Invalid,
+			Anomalies: map[string]resultsJSONAnomaly{},
+		}
+		for kind, s := range c.Anomalies {
+			a := resultsJSONAnomaly{Count: s.Count}
+			for _, e := range s.Examples {
+				a.Examples = append(a.Examples, resultsJSONAnomalyExample(e))
+			}
+			obj.ResultCheck.Anomalies[kind] = a
+		}
+	}
+	for _, e := range r.Endpoints {
+		latencies := map[string]int64{"mean": int64(e.Mean), "max": int64(e.Max)}
+		for i, percentile := range r.PercentilesLabels {
+			latencies[percentileLabel(percentile)] = int64(e.Percentiles[i])
+		}
+		obj.Endpoints = append(obj.Endpoints, resultsJSONEndpoint{
+			Origin:     e.Origin,
+			Successful: e.Successful,
+			Failed:     e.Failed,
+			QPS:        e.QueriesPerSecond,
+			Latencies:  latencies,
+		})
+	}
+	return obj
+}
+
+func (r Results) WriteJsonTo(w io.Writer) (int, error) {
+	bytes, err := json.MarshalIndent(r.toJSON(), "", "  ")
+	if err != nil {
+		return 0, err
+	}
+
+	return w.Write(bytes)
+}
@@ -0,0 +1,73 @@
+package benchmark
+
+import (
+	"context"
+	"net"
+
+	milvusClient "github.com/xiaocai2333/milvus-sdk-go/v2/client"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// calibrationOrigin is the origin recorded for calibration runs.
+const calibrationOrigin = "calibration"
+
+// noopResponse holds an empty status, field 1 of all Milvus responses, which
+// the SDK takes for a successful call.
+var noopResponse = []byte{0x0a, 0x00}
+
+// noopMilvus is an in-process Milvus answering every call at once with an
+// empty response. A run against it goes through the SDK, grpc and the
+// recording of the benchmarker but not the network nor Milvus, so its
+// latencies are the overhead of the benchmarker itself.
+type noopMilvus struct {
+	lis *bufconn.Listener
+	srv *grpc.Server
+}
+
+func startNoopMilvus() *noopMilvus {
+	m := &noopMilvus{
+		lis: bufconn.Listen(1 << 20),
+		srv: grpc.NewServer(grpc.CustomCodec(rawCodec{}), grpc.UnknownServiceHandler(
+			func(_ interface{}, stream grpc.ServerStream) error {
+				var req []byte
+				if err := stream.RecvMsg(&req); err != nil {
+					return err
+				}
+				resp := noopResponse
+				return stream.SendMsg(&resp)
+			})),
+	}
+	go m.srv.Serve(m.lis)
+	return m
+}
+
+func (m *noopMilvus) dial(ctx context.Context) (milvusClient.Client, error) {
+	opts, err := dialOptions(Connection{})
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return m.lis.Dial()
+	}))
+	return milvusClient.NewGrpcClient(ctx, calibrationOrigin, opts...)
+}
+
+func (m *noopMilvus) Close() {
+	m.srv.Stop()
+}
+
+// calibrationEndpoints starts a noopMilvus and connects to it, close stops
+// both.
+func calibrationEndpoints(ctx context.Context) (eps []endpoint, close func(), err error) {
+	m := startNoopMilvus()
+	client, err := m.dial(ctx)
+	if err != nil {
+		m.Close()
+		return nil, nil, err
+	}
+	return []endpoint{{origin: calibrationOrigin, client: client}}, func() {
+		client.Close()
+		m.Close()
+	}, nil
+}
@@ -0,0 +1,27 @@
+package benchmark
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun_calibrate(t *testing.T) {
+	cfg := testConfig("")
+	cfg.Calibrate = true
+	cfg.CheckResults = true
+	r, err := Run(context.Background(), cfg, Queries{{1, 2}})
+	assert.Nil(t, err)
+	assert.Equal(t, 10, r.Successful)
+	assert.Equal(t, calibrationOrigin, r.Run.Server.Version)
+	assert.True(t, r.Run.toJSON().Config.Calibration)
+	assert.Equal(t, 10, r.Payload.Calls)
+	assert.NotNil(t, r.ClientLoad)
+	// the no-op backend returns no hits
+	assert.Equal(t, 10, r.ResultCheck.Anomalies[AnomalyEmpty].Count)
+
+	cfg.Agents = 2
+	_, err = Run(context.Background(), cfg, Queries{{1, 2}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,188 @@
+package benchmark
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// A run is flagged as CPU-bound if the benchmarker kept more than
+// cpuBoundShare of its cores busy, or if its goroutines waited more than
+// schedLatencyBound to be scheduled at the 99th percentile.
+const (
+	cpuBoundShare     = 0.8
+	schedLatencyBound = time.Millisecond
+)
+
+// loadSampleInterval is how often the cpu usage of the process is sampled.
+var loadSampleInterval = 250 * time.Millisecond
+
+const schedLatenciesMetric = "/sched/latencies:seconds"
+
+// ClientLoad is how busy the benchmarker itself was during a run. CPU usage
+// is in cores, Cores is GOMAXPROCS.
+type ClientLoad struct {
+	Cores       int
+	CPUUsage    float64
+	PeakCPU     float64
+	SchedP50    time.Duration
+	SchedP99    time.Duration
+	SchedMax    time.Duration
+	CPUMeasured bool
+	// CPUBound is set if the latencies likely include time the requests
+	// waited for the benchmarker rather than for Milvus.
+	CPUBound bool
+}
+
+func (l ClientLoad) String() string {
+	s := fmt.Sprintf("Scheduler latency: p50 %s, p99 %s, max %s\n", l.SchedP50, l.SchedP99, l.SchedMax)
+	if l.CPUMeasured {
+		s = fmt.Sprintf("CPU: %.2f of %d cores, peak %.2f\n", l.CPUUsage, l.Cores, l.PeakCPU) + s
+	}
+	if l.CPUBound {
+		s += "WARNING: the benchmarker was likely CPU-bound, the latencies include its own overhead\n"
+	}
+	return s
+}
+
+func (l *ClientLoad) classify() {
+	l.CPUBound = l.SchedP99 > schedLatencyBound ||
+		(l.CPUMeasured && l.Cores > 0 && l.PeakCPU >= cpuBoundShare*float64(l.Cores))
+}
+
+// merge adds the load of another agent of a distributed run, the busiest
+// agent tells whether the run was CPU-bound.
+func (l *ClientLoad) merge(o *ClientLoad) {
+	if o.PeakCPU > l.PeakCPU || l.Cores == 0 {
+		l.Cores, l.CPUUsage, l.PeakCPU, l.CPUMeasured = o.Cores, o.CPUUsage, o.PeakCPU, o.CPUMeasured
+	}
+	if o.SchedP50 > l.SchedP50 {
+		l.SchedP50 = o.SchedP50
+	}
+	if o.SchedP99 > l.SchedP99 {
+		l.SchedP99 = o.SchedP99
+	}
+	if o.SchedMax > l.SchedMax {
+		l.SchedMax = o.SchedMax
+	}
+	l.CPUBound = l.CPUBound || o.CPUBound
+}
+
+// loadSampler samples the cpu time of the process until stopped, the
+// scheduler latencies come from the runtime.
+type loadSampler struct {
+	stop    chan struct{}
+	done    chan struct{}
+	start   time.Time
+	cpu0    time.Duration
+	sched0  *metrics.Float64Histogram
+	m       sync.Mutex
+	peakCPU float64
+}
+
+func startLoadSampler() *loadSampler {
+	s := &loadSampler{
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		start:  time.Now(),
+		sched0: schedLatencies(),
+	}
+	s.cpu0, _ = processCPUTime()
+	go s.loop()
+	return s
+}
+
+func (s *loadSampler) loop() {
+	defer close(s.done)
+	ticker := time.NewTicker(loadSampleInterval)
+	defer ticker.Stop()
+	last, lastCPU := s.start, s.cpu0
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-ticker.C:
+			cpu, ok := processCPUTime()
+			if !ok {
+				continue
+			}
+			if usage := float64(cpu-lastCPU) / float64(now.Sub(last)); usage > 0 {
+				s.m.Lock()
+				if usage > s.peakCPU {
+					s.peakCPU = usage
+				}
+				s.m.Unlock()
+			}
+			last, lastCPU = now, cpu
+		}
+	}
+}
+
+// Stop ends the sampling and returns the load since the start.
+func (s *loadSampler) Stop() *ClientLoad {
+	close(s.stop)
+	<-s.done
+	out := &ClientLoad{Cores: runtime.GOMAXPROCS(0)}
+	if cpu, ok := processCPUTime(); ok {
+		out.CPUMeasured = true
+		out.CPUUsage = float64(cpu-s.cpu0) / float64(time.Since(s.start))
+		s.m.Lock()
+		out.PeakCPU = math.Max(s.peakCPU, out.CPUUsage)
+		s.m.Unlock()
+	}
+	if sched := schedLatencies(); sched != nil && s.sched0 != nil {
+		counts := make([]uint64, len(sched.Counts))
+		for i := range counts {
+			counts[i] = sched.Counts[i] - s.sched0.Counts[i]
+		}
+		out.SchedP50 = histogramQuantile(counts, sched.Buckets, 0.5)
+		out.SchedP99 = histogramQuantile(counts, sched.Buckets, 0.99)
+		out.SchedMax = histogramQuantile(counts, sched.Buckets, 1)
+	}
+	out.classify()
+	return out
+}
+
+func schedLatencies() *metrics.Float64Histogram {
+	sample := []metrics.Sample{{Name: schedLatenciesMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindFloat64Histogram {
+		return nil
+	}
+	h := sample[0].Value.Float64Histogram()
+	return &metrics.Float64Histogram{
+		Counts:  append([]uint64{}, h.Counts...),
+		Buckets: h.Buckets,
+	}
+}
+
+// histogramQuantile returns the upper bound of the bucket holding the
+// quantile q of counts, buckets[i] and buckets[i+1] bound counts[i].
+func histogramQuantile(counts []uint64, buckets []float64, q float64) time.Duration {
+	var total uint64
+	for _, c := range counts {
+		total += c
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(total)))
+	if rank < 1 {
+		rank = 1
+	}
+	var seen uint64
+	for i, c := range counts {
+		seen += c
+		if seen >= rank {
+			upper := buckets[i+1]
+			if math.IsInf(upper, 1) {
+				upper = buckets[i]
+			}
+			return time.Duration(upper * float64(time.Second))
+		}
+	}
+	return 0
+}
@@ -0,0 +1,58 @@
+package benchmark
+
+import (
+	"math"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogramQuantile(t *testing.T) {
+	buckets := []float64{math.Inf(-1), 0.0001, 0.001, 0.01, math.Inf(1)}
+	counts := []uint64{0, 90, 9, 1}
+	assert.Equal(t, time.Millisecond, histogramQuantile(counts, buckets, 0.5))
+	assert.Equal(t, 10*time.Millisecond, histogramQuantile(counts, buckets, 0.99))
+	// the last bucket is unbounded, its lower bound is returned
+	assert.Equal(t, 10*time.Millisecond, histogramQuantile(counts, buckets, 1))
+	assert.Equal(t, time.Duration(0), histogramQuantile(make([]uint64, 4), buckets, 0.99))
+}
+
+func TestClientLoad_classify(t *testing.T) {
+	l := ClientLoad{Cores: 4, CPUMeasured: true, CPUUsage: 1, PeakCPU: 2, SchedP99: 100 * time.Microsecond}
+	l.classify()
+	assert.False(t, l.CPUBound)
+	l.PeakCPU = 3.5
+	l.classify()
+	assert.True(t, l.CPUBound)
+	assert.Contains(t, l.String(), "CPU-bound")
+
+	l = ClientLoad{Cores: 4, SchedP99: 5 * time.Millisecond}
+	l.classify()
+	assert.True(t, l.CPUBound)
+
+	merged := &ClientLoad{}
+	merged.merge(&ClientLoad{Cores: 4, CPUMeasured: true, PeakCPU: 1, SchedP99: time.Millisecond})
+	merged.merge(&ClientLoad{Cores: 8, CPUMeasured: true, PeakCPU: 7, SchedMax: time.Second, CPUBound: true})
+	assert.Equal(t, 8, merged.Cores)
+	assert.Equal(t, time.Millisecond, merged.SchedP99)
+	assert.Equal(t, time.Second, merged.SchedMax)
+	assert.True(t, merged.CPUBound)
+}
+
+func TestLoadSampler(t *testing.T) {
+	defer func(d time.Duration) { loadSampleInterval = d }(loadSampleInterval)
+	loadSampleInterval = 10 * time.Millisecond
+	s := startLoadSampler()
+	deadline := time.Now().Add(50 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		// keep a core busy
+	}
+	l := s.Stop()
+	assert.Equal(t, runtime.GOMAXPROCS(0), l.Cores)
+	if l.CPUMeasured {
+		assert.True(t, l.CPUUsage > 0.1, l.CPUUsage)
+		assert.True(t, l.PeakCPU >= l.CPUUsage)
+	}
+}
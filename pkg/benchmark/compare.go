@@ -0,0 +1,195 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Thresholds are the regressions tolerated when comparing a candidate run
+// against a baseline.
+type Thresholds struct {
+	// QPSDrop and LatencyIncrease are in percent of the baseline.
+	QPSDrop         float64
+	LatencyIncrease float64
+	// ErrorRateIncrease is in percentage points.
+	ErrorRateIncrease float64
+}
+
+// readResultsJSON reads results written with WriteJsonTo, it fails on json
+// without their throughput and latencies, which would compare as passing.
+func readResultsJSON(fname string) (resultsJSON, error) {
+	var obj resultsJSON
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return obj, err
+	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return obj, errors.Wrapf(err, "parse %q", fname)
+	}
+	for _, key := range []string{"throughput", "latencies"} {
+		if _, ok := keys[key]; !ok {
+			return obj, errors.Errorf("parse %q: missing %q, not json results", fname, key)
+		}
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return obj, errors.Wrapf(err, "parse %q", fname)
+	}
+	return obj, nil
+}
+
+// MetricDiff is the comparison of one metric, Passed is false if it
+// regressed beyond its threshold.
+type MetricDiff struct {
+	Name string
+	// Baseline or Candidate is NaN if the metric is missing from that run,
+	// which fails it.
+	Baseline  float64
+	Candidate float64
+	// Change is relative to the baseline, 0.1 means 10% higher.
+	Change  float64
+	Latency bool
+	Passed  bool
+}
+
+// latency returns the latency name of r, NaN if r does not have it.
+func latency(r resultsJSON, name string) float64 {
+	if v, ok := r.Latencies[name]; ok {
+		return float64(v)
+	}
+	return math.NaN()
+}
+
+func relativeChange(baseline, candidate float64) float64 {
+	if baseline == 0 {
+		if candidate == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return (candidate - baseline) / baseline
+}
+
+func errorRate(m resultsJSONMetadata) float64 {
+	if m.Total == 0 {
+		return 0
+	}
+	return float64(m.Failed) / float64(m.Total) * 100
+}
+
+// comparedLatencies returns the mean and percentile latencies present in
+// either results, percentiles in ascending order.
+func comparedLatencies(baseline, candidate resultsJSON) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, latencies := range []map[string]int64{baseline.Latencies, candidate.Latencies} {
+		for name := range latencies {
+			if !seen[name] && (name == "mean" || strings.HasPrefix(name, "p")) {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Slice(names, func(a, b int) bool {
+		pa, erra := strconv.ParseFloat(strings.TrimPrefix(names[a], "p"), 64)
+		pb, errb := strconv.ParseFloat(strings.TrimPrefix(names[b], "p"), 64)
+		if erra != nil || errb != nil {
+			return erra != nil && errb == nil
+		}
+		return pa < pb
+	})
+	return names
+}
+
+// CompareFiles compares the json results of a candidate run against those of
+// a baseline, both written with WriteJsonTo.
+func CompareFiles(baseline, candidate string, th Thresholds) ([]MetricDiff, error) {
+	b, err := readResultsJSON(baseline)
+	if err != nil {
+		return nil, err
+	}
+	c, err := readResultsJSON(candidate)
+	if err != nil {
+		return nil, err
+	}
+	return compareResults(b, c, th), nil
+}
+
+// Regressed reports whether any of diffs did not pass.
+func Regressed(diffs []MetricDiff) bool {
+	for _, d := range diffs {
+		if !d.Passed {
+			return true
+		}
+	}
+	return false
+}
+
+func compareResults(baseline, candidate resultsJSON, th Thresholds) []MetricDiff {
+	var diffs []MetricDiff
+
+	qps := MetricDiff{
+		Name:      "qps",
+		Baseline:  baseline.Throughput.QPS,
+		Candidate: candidate.Throughput.QPS,
+	}
+	qps.Change = relativeChange(qps.Baseline, qps.Candidate)
+	qps.Passed = qps.Change >= -th.QPSDrop/100
+	diffs = append(diffs, qps)
+
+	for _, name := range comparedLatencies(baseline, candidate) {
+		d := MetricDiff{
+			Name:      name,
+			Baseline:  latency(baseline, name),
+			Candidate: latency(candidate, name),
+			Latency:   true,
+		}
+		d.Change = relativeChange(d.Baseline, d.Candidate)
+		d.Passed = d.Change <= th.LatencyIncrease/100
+		diffs = append(diffs, d)
+	}
+
+	errRate := MetricDiff{
+		Name:      "error_rate",
+		Baseline:  errorRate(baseline.Metadata),
+		Candidate: errorRate(candidate.Metadata),
+	}
+	errRate.Change = relativeChange(errRate.Baseline, errRate.Candidate)
+	errRate.Passed = errRate.Candidate-errRate.Baseline <= th.ErrorRateIncrease
+	diffs = append(diffs, errRate)
+	return diffs
+}
+
+func formatMetric(d MetricDiff, v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "missing"
+	case d.Latency:
+		return fmt.Sprint(time.Duration(v))
+	}
+	return fmt.Sprintf("%.3f", v)
+}
+
+func WriteDiffsTo(w io.Writer, diffs []MetricDiff) (int, error) {
+	b := strings.Builder{}
+	b.WriteString(fmt.Sprintf("%-12s %16s %16s %10s  %s\n", "metric", "baseline", "candidate", "change", "result"))
+	for _, d := range diffs {
+		baseline, candidate := formatMetric(d, d.Baseline), formatMetric(d, d.Candidate)
+		result := "pass"
+		if !d.Passed {
+			result = "FAIL"
+		}
+		b.WriteString(fmt.Sprintf("%-12s %16s %16s %+9.2f%%  %s\n",
+			d.Name, baseline, candidate, d.Change*100, result))
+	}
+	return w.Write([]byte(b.String()))
+}
@@ -0,0 +1,74 @@
+package benchmark
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeResultsFile(t *testing.T, dir, name string, r Results) string {
+	fname := filepath.Join(dir, name)
+	f, err := os.Create(fname)
+	assert.Nil(t, err)
+	defer f.Close()
+	_, err = r.WriteJsonTo(f)
+	assert.Nil(t, err)
+	return fname
+}
+
+func TestCompareResults(t *testing.T) {
+	dir := t.TempDir()
+	base := testResults()
+	baseline, err := readResultsJSON(writeResultsFile(t, dir, "baseline.json", base))
+	assert.Nil(t, err)
+
+	diffs := compareResults(baseline, baseline, Thresholds{})
+	names := make([]string, 0, len(diffs))
+	for _, d := range diffs {
+		assert.True(t, d.Passed, d.Name)
+		assert.Equal(t, float64(0), d.Change)
+		names = append(names, d.Name)
+	}
+	assert.Equal(t, []string{"qps", "mean", "p50", "p90", "p95", "p98", "p99", "error_rate"}, names)
+
+	slower := base
+	slower.QueriesPerSecond = base.QueriesPerSecond * 0.9
+	slower.Percentiles = append([]time.Duration{}, base.Percentiles...)
+	slower.Percentiles[4] = base.Percentiles[4] * 2
+	slower.Failed = base.Failed + 10
+	candidate, err := readResultsJSON(writeResultsFile(t, dir, "candidate.json", slower))
+	assert.Nil(t, err)
+
+	failed := map[string]bool{}
+	for _, d := range compareResults(baseline, candidate, Thresholds{QPSDrop: 5, LatencyIncrease: 10, ErrorRateIncrease: 1}) {
+		if !d.Passed {
+			failed[d.Name] = true
+		}
+	}
+	assert.Equal(t, map[string]bool{"qps": true, "p99": true, "error_rate": true}, failed)
+
+	diffs = compareResults(baseline, candidate, Thresholds{})
+
+	b := &strings.Builder{}
+	_, err = WriteDiffsTo(b, diffs)
+	assert.Nil(t, err)
+	assert.Contains(t, b.String(), "FAIL")
+
+	delete(candidate.Latencies, "p99")
+	for _, d := range compareResults(baseline, candidate, Thresholds{LatencyIncrease: 100}) {
+		if d.Name == "p99" {
+			assert.False(t, d.Passed)
+		}
+	}
+
+	_, err = readResultsJSON(filepath.Join(dir, "not_exist.json"))
+	assert.Error(t, err)
+	empty := filepath.Join(dir, "empty.json")
+	assert.Nil(t, os.WriteFile(empty, []byte("{}"), 0644))
+	_, err = readResultsJSON(empty)
+	assert.Error(t, err)
+}
@@ -0,0 +1,214 @@
+package benchmark
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Config describes a run. Mode and the Query fields are only recorded in the
+// results, the queries themselves come from the QuerySource given to Run.
+type Config struct {
+	SearchParams
+	Name string
+	Mode string
+	// Origin is the address of Milvus, or a comma separated list of
+	// endpoints such as the proxies of a cluster to spread the load over.
+	Origin string
+	// Balance is how the load is spread over the endpoints of Origin,
+	// BalanceRoundRobin if empty, BalanceScope whether it is per request,
+	// the default, or per worker.
+	Balance      string
+	BalanceScope string
+	// Connection holds the TLS settings and credentials used to reach Origin.
+	Connection   Connection
+	Nq           int
+	Parallel     int
+	QueryFile    string
+	QueryDataset string
+	QueryOffset  int
+	QueryRows    int
+	Total        int
+	// Assert holds assertions such as p99<50ms, checked together with those
+	// of the search params.
+	Assert []string
+	// Percentiles to report, DefaultPercentiles if empty.
+	Percentiles      []float64
+	PercentileMethod string
+	ProgressInterval time.Duration
+	// OnProgress, if set, is called with the progress of the run every
+	// ProgressInterval. It is not sent to agents.
+	OnProgress  func(Progress) `json:"-"`
+	MetricsAddr string
+	// StartAt delays the workers until the given time, it is set by the
+	// coordinator of a distributed run.
+	StartAt         time.Time
+	Agents          int
+	CoordinatorAddr string
+	AgentTimeout    time.Duration
+	// ReportInterval is the length of the intervals of Results.Series, no
+	// series is recorded if it is 0.
+	ReportInterval time.Duration
+	// CheckResults checks the hits of every search for anomalies such as
+	// unsorted scores, see Results.ResultCheck.
+	CheckResults bool
+	// NICSpeed is the speed of the client NIC in Mbit/s, to tell whether it
+	// limited the run. It is read from the system if 0.
+	NICSpeed int
+	// Calibrate runs against an in-process Milvus that answers at once
+	// instead of Origin, the latencies are then the overhead of the
+	// benchmarker per request.
+	Calibrate bool
+	// Seed is the seed of all the randomness of a run, every worker draws
+	// from its own stream derived from it. A seed is picked if it is 0 and
+	// recorded in the results either way.
+	Seed int64
+	// TraceOut, if set, is a file to write every request of the run to, as
+	// csv, ndjson or npy depending on its extension.
+	TraceOut string
+	// Capture, if set, is a file to record the requests of the run to, with
+	// their vectors, search params and timings, to be sent again by a run
+	// with Replay.
+	Capture string
+	// Replay is a file recorded by Capture whose requests are sent instead
+	// of those of the query source, with its search params, workers and
+	// number of requests. SearchParams must then be empty but for the
+	// assertions. ReplaySpeed scales the pace of the recording, 2
+	// sends twice as fast, 0 as fast as possible.
+	Replay      string
+	ReplaySpeed float64
+	// Retry says which failed searches are sent again, none by default.
+	Retry RetryPolicy
+	// ThinkTime is the pause of every worker between two of its requests,
+	// such as 100ms, uniform:50ms-150ms or exponential:100ms, so that
+	// Parallel models users rather than saturating clients.
+	ThinkTime string
+}
+
+// assertions returns the assertions given by flags followed by those in the
+// search params of the case.
+func (c Config) assertions() []string {
+	return append(append([]string{}, c.Assert...), c.SearchParams.Assertions...)
+}
+
+type SearchParams struct {
+	CollectionName string   `json:"collection_name"`
+	PartitionNames []string `json:"partition_names"`
+	FieldName      string   `json:"fieldName"`
+	IndexType      string   `json:"index_type"`
+	MetricType     string   `json:"metric_type"`
+	Params         struct {
+		Dim int `json:"dim"`
+		Ef  int `json:"ef"`
+	} `json:"params"`
+	Limit        int           `json:"limit"`
+	Expr         string        `json:"expr"`
+	OutputFields []string      `json:"output_fields"`
+	Timeout      time.Duration `json:"timeout"`
+	Assertions   []string      `json:"assertions"`
+	// ConsistencyLevel is one of Strong, Bounded, Session and Eventually,
+	// DefaultConsistencyLevel if empty. GuaranteeTimestamp, a hybrid
+	// timestamp of Milvus, can be given instead.
+	ConsistencyLevel   string `json:"consistency_level,omitempty"`
+	GuaranteeTimestamp uint64 `json:"guarantee_timestamp,omitempty"`
+}
+
+// validatePercentiles checks the percentiles and the method to report them
+// with, the only parts of c that analyzing a trace uses.
+func (c Config) validatePercentiles() error {
+	for _, p := range c.Percentiles {
+		if p <= 0 || p > 100 {
+			return errors.Errorf("percentile %v out of range (0, 100]", p)
+		}
+	}
+	switch c.PercentileMethod {
+	case PercentileNearestRank, PercentileLinear, "":
+	default:
+		return errors.Errorf("unsupported percentile method %q, must be one of [%s, %s]",
+			c.PercentileMethod, PercentileNearestRank, PercentileLinear)
+	}
+	return nil
+}
+
+// Validate checks the parts of c that do not depend on the query source.
+func (c Config) Validate() error {
+	if c.Replay != "" {
+		if c.Agents > 0 {
+			return errors.Errorf("a replay follows the workers of the recording, agents cannot be used")
+		}
+		if c.ReplaySpeed < 0 {
+			return errors.Errorf("replaySpeed must not be negative")
+		}
+		if c.ThinkTime != "" {
+			return errors.Errorf("a replay keeps the pace of its recording, thinkTime cannot be used")
+		}
+		// the assertions are the only search params not taken from the
+		// recording
+		params := c.SearchParams
+		params.Assertions = nil
+		if !reflect.DeepEqual(params, SearchParams{}) {
+			return errors.Errorf("a replay sends the search params of its recording, searchParams, consistencyLevel and guaranteeTimestamp cannot be set")
+		}
+	}
+	if c.Capture != "" && c.Agents > 0 {
+		return errors.Errorf("a workload is captured by the process running the workers, agents cannot be used")
+	}
+	if len(c.origins()) == 0 && !c.Calibrate {
+		return errors.Errorf("origin must be set")
+	}
+	if c.Calibrate && c.Agents > 0 {
+		return errors.Errorf("a calibration runs locally, agents cannot be used")
+	}
+	if err := c.validateBalance(); err != nil {
+		return err
+	}
+	if _, err := c.consistencyLevel(); err != nil {
+		return err
+	}
+	if c.CollectionName == "" && c.Replay == "" {
+		return errors.Errorf("collectionName must be set")
+	}
+	if c.Parallel < 1 && c.Replay == "" {
+		return errors.Errorf("parallel must be at least 1")
+	}
+	if c.Total < 1 && c.Replay == "" {
+		return errors.Errorf("total must be at least 1")
+	}
+	if err := c.validatePercentiles(); err != nil {
+		return err
+	}
+	if c.Agents < 0 {
+		return errors.Errorf("agents must not be negative")
+	}
+	if c.Agents > 0 && (c.Total < c.Agents || c.Parallel < c.Agents) {
+		return errors.Errorf("total and parallel must be at least the number of agents")
+	}
+	if err := c.Connection.validate(); err != nil {
+		return err
+	}
+	if err := c.Retry.validate(); err != nil {
+		return err
+	}
+	if _, err := parseThinkTime(c.ThinkTime); err != nil {
+		return err
+	}
+	if c.TraceOut != "" {
+		if c.Agents > 0 {
+			return errors.Errorf("a trace is written by the process running the workers, agents cannot be used")
+		}
+		if _, err := traceFormat(c.TraceOut); err != nil {
+			return err
+		}
+	}
+	if c.NICSpeed < 0 {
+		return errors.Errorf("nicSpeed must not be negative")
+	}
+	if c.QueryOffset < 0 || c.QueryRows < 0 {
+		return errors.Errorf("queryOffset and queryRows must not be negative")
+	}
+	if _, err := parseAssertions(c.assertions()); err != nil {
+		return err
+	}
+	return nil
+}
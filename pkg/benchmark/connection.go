@@ -0,0 +1,122 @@
+package benchmark
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io/ioutil"
+	"time"
+
+	"github.com/pkg/errors"
+	milvusClient "github.com/xiaocai2333/milvus-sdk-go/v2/client"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Connection describes how to connect to Milvus. TLS is used if TLS is set
+// or any of the files is given, the CA bundle defaults to the system one.
+// Username and Password, or Token, are sent with every call.
+type Connection struct {
+	TLS        bool
+	CAFile     string
+	CertFile   string
+	KeyFile    string
+	ServerName string
+	Username   string
+	Password   string
+	Token      string
+}
+
+func (c Connection) tlsEnabled() bool {
+	return c.TLS || c.CAFile != "" || c.CertFile != "" || c.KeyFile != "" || c.ServerName != ""
+}
+
+func (c Connection) validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return errors.Errorf("the client certificate and key must be given together")
+	}
+	if c.Token != "" && (c.Username != "" || c.Password != "") {
+		return errors.Errorf("either a token or a username and password can be given")
+	}
+	if c.Password != "" && c.Username == "" {
+		return errors.Errorf("a password needs a username")
+	}
+	return nil
+}
+
+// authorization is the value of the authorization metadata Milvus checks.
+func (c Connection) authorization() string {
+	switch {
+	case c.Token != "":
+		return base64.StdEncoding.EncodeToString([]byte(c.Token))
+	case c.Username != "":
+		return base64.StdEncoding.EncodeToString([]byte(c.Username + ":" + c.Password))
+	}
+	return ""
+}
+
+func (c Connection) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{ServerName: c.ServerName}
+	if c.CAFile != "" {
+		pem, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificate found in %q", c.CAFile)
+		}
+	}
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// authCredentials adds the authorization metadata to every call.
+type authCredentials struct {
+	authorization string
+	secure        bool
+}
+
+func (a authCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": a.authorization}, nil
+}
+
+func (a authCredentials) RequireTransportSecurity() bool {
+	return a.secure
+}
+
+func dialOptions(c Connection) ([]grpc.DialOption, error) {
+	opts := []grpc.DialOption{
+		grpc.WithBlock(),                   //block connect until healthy or timeout
+		grpc.WithTimeout(20 * time.Second), // set connect timeout to 20 Second
+		grpc.WithStatsHandler(payloadHandler{}),
+	}
+	if c.tlsEnabled() {
+		tlsConfig, err := c.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+	if auth := c.authorization(); auth != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(authCredentials{authorization: auth, secure: c.tlsEnabled()}))
+	}
+	return opts, nil
+}
+
+func dial(ctx context.Context, origin string, c Connection) (milvusClient.Client, error) {
+	opts, err := dialOptions(c)
+	if err != nil {
+		return nil, err
+	}
+	return milvusClient.NewGrpcClient(ctx, origin, opts...)
+}
@@ -0,0 +1,141 @@
+package benchmark
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+// newTestCert issues a certificate signed by parent, or a self-signed CA if
+// parent is nil.
+func newTestCert(t *testing.T, parent *testCert, name string, usage x509.ExtKeyUsage) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		tmpl.DNSNames = []string{name}
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+		tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+// write writes the certificate and its key as pem files into dir.
+func (c *testCert) write(t *testing.T, dir, name string) (certFile, keyFile string) {
+	certFile = filepath.Join(dir, name+".pem")
+	keyFile = filepath.Join(dir, name+"-key.pem")
+	assert.Nil(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600))
+	key, err := x509.MarshalECPrivateKey(c.key)
+	assert.Nil(t, err)
+	assert.Nil(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: key}), 0600))
+	return certFile, keyFile
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+func TestRun_tls(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, nil, "test ca", 0)
+	server := newTestCert(t, ca, "milvus.test", x509.ExtKeyUsageServerAuth)
+	client := newTestCert(t, ca, "benchmarker", x509.ExtKeyUsageClientAuth)
+	caFile, _ := ca.write(t, dir, "ca")
+	certFile, keyFile := client.write(t, dir, "client")
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	milvus := newFakeMilvus(t, nil, grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{server.tlsCertificate()},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})))
+
+	cfg := testConfig(milvus.addr)
+	cfg.Connection = Connection{
+		CAFile:     caFile,
+		CertFile:   certFile,
+		KeyFile:    keyFile,
+		ServerName: "milvus.test",
+		Username:   "root",
+		Password:   "Milvus",
+	}
+	r, err := Run(context.Background(), cfg, Queries{{1, 2}})
+	assert.Nil(t, err)
+	assert.Equal(t, 10, r.Successful)
+	assert.Equal(t, []string{base64.StdEncoding.EncodeToString([]byte("root:Milvus"))}, milvus.lastAuthorization())
+	assert.True(t, r.Run.toJSON().Config.TLS)
+	assert.Equal(t, "root", r.Run.toJSON().Config.Username)
+
+	cfg.Connection = Connection{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, ServerName: "milvus.test", Token: "secret"}
+	_, err = Run(context.Background(), cfg, Queries{{1, 2}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{base64.StdEncoding.EncodeToString([]byte("secret"))}, milvus.lastAuthorization())
+
+	// a failed handshake is retried by the dial until the deadline
+	for _, conn := range []Connection{
+		// the certificate is not issued for the address dialed
+		{CAFile: caFile, CertFile: certFile, KeyFile: keyFile},
+		// the server does not trust a client without a certificate
+		{CAFile: caFile, ServerName: "milvus.test"},
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		cfg.Connection = conn
+		_, err = Run(ctx, cfg, Queries{{1, 2}})
+		cancel()
+		assert.Error(t, err)
+	}
+}
+
+func TestConnection_validate(t *testing.T) {
+	assert.Nil(t, Connection{}.validate())
+	assert.Nil(t, Connection{Username: "root", Password: "Milvus"}.validate())
+	assert.Error(t, Connection{CertFile: "client.pem"}.validate())
+	assert.Error(t, Connection{Username: "root", Token: "secret"}.validate())
+	assert.Error(t, Connection{Password: "Milvus"}.validate())
+}
+
+func TestDialOptions_errors(t *testing.T) {
+	_, err := dialOptions(Connection{CAFile: filepath.Join(t.TempDir(), "missing.pem")})
+	assert.Error(t, err)
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	assert.Nil(t, ioutil.WriteFile(empty, nil, 0600))
+	_, err = dialOptions(Connection{CAFile: empty})
+	assert.Error(t, err)
+}
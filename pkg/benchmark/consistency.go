@@ -0,0 +1,74 @@
+package benchmark
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Consistency levels of SearchParams.ConsistencyLevel. The SDK has no notion
+// of them, a level is sent the way pymilvus does it, as the guarantee
+// timestamp of the search.
+const (
+	ConsistencyStrong     = "Strong"
+	ConsistencyBounded    = "Bounded"
+	ConsistencySession    = "Session"
+	ConsistencyEventually = "Eventually"
+	// ConsistencyCustomized is recorded for searches sent with an explicit
+	// guarantee timestamp.
+	ConsistencyCustomized = "Customized"
+)
+
+// DefaultConsistencyLevel is what the searches have always been sent with.
+const DefaultConsistencyLevel = ConsistencyEventually
+
+// Guarantee timestamps of the consistency levels. Strong waits for all the
+// writes until the search arrived, Bounded for those until the graceful time
+// of the proxy and Eventually for none. Session waits for the last write of
+// the session, the benchmarker does not write and so it is Eventually.
+const (
+	strongTimestamp     uint64 = 0
+	eventuallyTimestamp uint64 = 1
+	boundedTimestamp    uint64 = 2
+)
+
+var consistencyLevels = []string{ConsistencyStrong, ConsistencyBounded, ConsistencySession, ConsistencyEventually}
+
+// consistencyLevel returns the canonical name of the level of p, levels are
+// matched case insensitively.
+func (p SearchParams) consistencyLevel() (string, error) {
+	if p.ConsistencyLevel == "" {
+		if p.GuaranteeTimestamp != 0 {
+			return ConsistencyCustomized, nil
+		}
+		return DefaultConsistencyLevel, nil
+	}
+	for _, level := range consistencyLevels {
+		if strings.EqualFold(p.ConsistencyLevel, level) {
+			if p.GuaranteeTimestamp != 0 {
+				return "", errors.Errorf("either a consistency level or a guarantee timestamp can be given")
+			}
+			return level, nil
+		}
+	}
+	return "", errors.Errorf("unsupported consistency level %q, must be one of %v",
+		p.ConsistencyLevel, consistencyLevels)
+}
+
+// guaranteeTimestamp returns the guarantee timestamp of the searches.
+func (p SearchParams) guaranteeTimestamp() (uint64, error) {
+	level, err := p.consistencyLevel()
+	if err != nil {
+		return 0, err
+	}
+	switch level {
+	case ConsistencyCustomized:
+		return p.GuaranteeTimestamp, nil
+	case ConsistencyStrong:
+		return strongTimestamp, nil
+	case ConsistencyBounded:
+		return boundedTimestamp, nil
+	default:
+		return eventuallyTimestamp, nil
+	}
+}
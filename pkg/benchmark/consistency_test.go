@@ -0,0 +1,87 @@
+package benchmark
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestSearchParams_guaranteeTimestamp(t *testing.T) {
+	for _, c := range []struct {
+		level     string
+		timestamp uint64
+		want      uint64
+		wantLevel string
+	}{
+		{"", 0, eventuallyTimestamp, ConsistencyEventually},
+		{"Strong", 0, strongTimestamp, ConsistencyStrong},
+		{"bounded", 0, boundedTimestamp, ConsistencyBounded},
+		{"SESSION", 0, eventuallyTimestamp, ConsistencySession},
+		{"Eventually", 0, eventuallyTimestamp, ConsistencyEventually},
+		{"", 434848944848453633, 434848944848453633, ConsistencyCustomized},
+	} {
+		p := SearchParams{ConsistencyLevel: c.level, GuaranteeTimestamp: c.timestamp}
+		ts, err := p.guaranteeTimestamp()
+		assert.Nil(t, err, c.level)
+		assert.Equal(t, c.want, ts, c.level)
+		level, _ := p.consistencyLevel()
+		assert.Equal(t, c.wantLevel, level)
+	}
+
+	_, err := SearchParams{ConsistencyLevel: "linearizable"}.guaranteeTimestamp()
+	assert.Error(t, err)
+	_, err = SearchParams{ConsistencyLevel: "Strong", GuaranteeTimestamp: 3}.guaranteeTimestamp()
+	assert.Error(t, err)
+}
+
+func TestConfigCaseName_consistency(t *testing.T) {
+	cfg := Config{Nq: 1, Parallel: 2}
+	cfg.IndexType = "HNSW"
+	cfg.MetricType = "L2"
+	assert.Equal(t, "HNSW_L2_ef0_top0_nq1_p2", cfg.CaseName())
+	cfg.ConsistencyLevel = "strong"
+	assert.Equal(t, "HNSW_L2_ef0_top0_nq1_p2_Strong", cfg.CaseName())
+}
+
+// requestGuaranteeTimestamp returns the guarantee_timestamp of a SearchRequest.
+func requestGuaranteeTimestamp(req []byte) uint64 {
+	for len(req) > 0 {
+		num, typ, n := protowire.ConsumeTag(req)
+		req = req[n:]
+		if num == 11 && typ == protowire.VarintType {
+			v, _ := protowire.ConsumeVarint(req)
+			return v
+		}
+		req = req[protowire.ConsumeFieldValue(num, typ, req):]
+	}
+	return 0
+}
+
+func TestRun_consistency(t *testing.T) {
+	var sent uint64
+	milvus := newFakeMilvus(t, func(method string, req []byte) ([]byte, error) {
+		if method == searchMethod {
+			atomic.StoreUint64(&sent, requestGuaranteeTimestamp(req))
+		}
+		return nil, nil
+	})
+	cfg := testConfig(milvus.addr)
+	r, err := Run(context.Background(), cfg, Queries{{1, 2}})
+	assert.Nil(t, err)
+	assert.Equal(t, eventuallyTimestamp, atomic.LoadUint64(&sent))
+	assert.Equal(t, ConsistencyEventually, r.Run.toJSON().Config.ConsistencyLevel)
+
+	cfg.ConsistencyLevel = "Bounded"
+	r, err = Run(context.Background(), cfg, Queries{{1, 2}})
+	assert.Nil(t, err)
+	assert.Equal(t, boundedTimestamp, atomic.LoadUint64(&sent))
+	assert.Equal(t, ConsistencyBounded, r.Run.toJSON().Config.ConsistencyLevel)
+	assert.Equal(t, boundedTimestamp, r.Run.toJSON().Config.GuaranteeTimestamp)
+
+	cfg.ConsistencyLevel = "Sometimes"
+	_, err = Run(context.Background(), cfg, Queries{{1, 2}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,12 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package benchmark
+
+import "time"
+
+// processCPUTime is not implemented on this platform, only the scheduler
+// latency tells whether a run was CPU-bound.
+func processCPUTime() (time.Duration, bool) {
+	return 0, false
+}
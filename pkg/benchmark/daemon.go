@@ -0,0 +1,332 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The daemon serves runs over HTTP JSON-RPC 2.0 so that drivers such as
+// gowrapper.py can submit many cases to a single process, which keeps its
+// connections to Milvus open. A request is POSTed to any path:
+//
+//	{"jsonrpc": "2.0", "id": 1, "method": "Benchmark.Run", "params": {...}}
+//
+// and answered with newline delimited json: a Benchmark.Progress
+// notification per progress interval while the run is in progress, then the
+// response holding the results in the format of WriteJsonTo.
+
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcRunFailed      = -32000
+)
+
+// Job is a run submitted to the daemon, the queries are either given inline
+// or read from QueryFile on the host of the daemon. Its files other than the
+// TLS ones are relative to the directory of the daemon, see NewDaemon.
+type Job struct {
+	Name             string       `json:"name"`
+	Origin           string       `json:"origin"`
+	Balance          string       `json:"balance"`
+	BalanceScope     string       `json:"balance_scope"`
+	TLS              bool         `json:"tls"`
+	TLSCAFile        string       `json:"tls_ca_file"`
+	TLSCertFile      string       `json:"tls_cert_file"`
+	TLSKeyFile       string       `json:"tls_key_file"`
+	TLSServerName    string       `json:"tls_server_name"`
+	Username         string       `json:"username"`
+	Password         string       `json:"password"`
+	Token            string       `json:"token"`
+	SearchParams     SearchParams `json:"search_params"`
+	Queries          Queries      `json:"queries"`
+	QueryFile        string       `json:"query_file"`
+	QueryDataset     string       `json:"query_dataset"`
+	QueryOffset      int          `json:"query_offset"`
+	QueryRows        int          `json:"query_rows"`
+	Parallel         int          `json:"parallel"`
+	Total            int          `json:"total"`
+	Percentiles      []float64    `json:"percentiles"`
+	PercentileMethod string       `json:"percentile_method"`
+	Assertions       []string     `json:"assertions"`
+	CheckResults     bool         `json:"check_results"`
+	Calibrate        bool         `json:"calibrate"`
+	Seed             int64        `json:"seed"`
+	TraceOut         string       `json:"trace_out"`
+	Capture          string       `json:"capture"`
+	Replay           string       `json:"replay"`
+	// ReplaySpeed is 1, the pace of the recording, if it is not set.
+	ReplaySpeed      *float64 `json:"replay_speed"`
+	RetryMaxAttempts int      `json:"retry_max_attempts"`
+	// RetryBackoff and RetryMaxBackoff are in seconds.
+	RetryBackoff    float64  `json:"retry_backoff"`
+	RetryMaxBackoff float64  `json:"retry_max_backoff"`
+	RetryCodes      []string `json:"retry_codes"`
+	ThinkTime       string   `json:"think_time"`
+	// ProgressInterval is in seconds, no progress is sent if it is 0.
+	ProgressInterval float64 `json:"progress_interval"`
+	HistoryFile      string  `json:"history_file"`
+	Report           string  `json:"report"`
+}
+
+func (j Job) config() Config {
+	cfg := Config{
+		SearchParams: j.SearchParams,
+		Name:         j.Name,
+		Mode:         "serve",
+		Origin:       j.Origin,
+		Balance:      j.Balance,
+		BalanceScope: j.BalanceScope,
+		Connection: Connection{
+			TLS:        j.TLS,
+			CAFile:     j.TLSCAFile,
+			CertFile:   j.TLSCertFile,
+			KeyFile:    j.TLSKeyFile,
+			ServerName: j.TLSServerName,
+			Username:   j.Username,
+			Password:   j.Password,
+			Token:      j.Token,
+		},
+		Parallel:     j.Parallel,
+		QueryFile:    j.QueryFile,
+		QueryDataset: j.QueryDataset,
+		QueryOffset:  j.QueryOffset,
+		QueryRows:    j.QueryRows,
+		Total:        j.Total,
+		Assert:       j.Assertions,
+		CheckResults: j.CheckResults,
+		Calibrate:    j.Calibrate,
+		Seed:         j.Seed,
+		TraceOut:     j.TraceOut,
+		Capture:      j.Capture,
+		Replay:       j.Replay,
+		ReplaySpeed:  1,
+		ThinkTime:    j.ThinkTime,
+		Retry: RetryPolicy{
+			MaxAttempts: j.RetryMaxAttempts,
+			Backoff:     time.Duration(j.RetryBackoff * float64(time.Second)),
+			MaxBackoff:  time.Duration(j.RetryMaxBackoff * float64(time.Second)),
+			Codes:       j.RetryCodes,
+		},
+		Percentiles:      j.Percentiles,
+		PercentileMethod: j.PercentileMethod,
+		ProgressInterval: time.Duration(j.ProgressInterval * float64(time.Second)),
+		ReportInterval:   time.Second,
+	}
+	if cfg.Parallel == 0 {
+		cfg.Parallel = 1
+	}
+	if j.ReplaySpeed != nil {
+		cfg.ReplaySpeed = *j.ReplaySpeed
+	}
+	if cfg.Total == 0 {
+		cfg.Total = 1
+	}
+	if cfg.QueryDataset == "" {
+		cfg.QueryDataset = DefaultQueryDataset
+	}
+	return cfg
+}
+
+// confine makes the files read and written by j relative to dir and fails if
+// any of them is outside of it or if dir is empty, so that clients cannot
+// reach the rest of the host.
+func (j *Job) confine(dir string) error {
+	for _, f := range []struct {
+		name string
+		path *string
+	}{
+		{"query_file", &j.QueryFile},
+		{"trace_out", &j.TraceOut},
+		{"capture", &j.Capture},
+		{"replay", &j.Replay},
+		{"history_file", &j.HistoryFile},
+		{"report", &j.Report},
+	} {
+		if *f.path == "" {
+			continue
+		}
+		if dir == "" {
+			return errors.Errorf("%s is not allowed, the daemon has no directory for files", f.name)
+		}
+		clean := filepath.Clean(*f.path)
+		if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return errors.Errorf("%s %q must be relative to the directory of the daemon", f.name, *f.path)
+		}
+		*f.path = filepath.Join(dir, clean)
+	}
+	return nil
+}
+
+func (j Job) queries(cfg Config) (Queries, error) {
+	if j.Replay != "" {
+		// the queries come from the recording
+		return nil, nil
+	}
+	if len(j.Queries) > 0 {
+		return sliceQueries(j.Queries, j.QueryOffset, j.QueryRows)
+	}
+	if j.QueryFile == "" {
+		return nil, errors.New("either queries or query_file must be set")
+	}
+	return ReadQueries(cfg)
+}
+
+func (j Job) sinks() []Sink {
+	var sinks []Sink
+	if j.HistoryFile != "" {
+		sinks = append(sinks, HistorySink(j.HistoryFile))
+	}
+	if j.Report != "" {
+		sinks = append(sinks, ReportSink(j.Report, j.HistoryFile))
+	}
+	return sinks
+}
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// progressJSON is the params of a Benchmark.Progress notification,
+// durations are in nanoseconds like the latencies of the results.
+type progressJSON struct {
+	ID        json.RawMessage `json:"id"`
+	Elapsed   int64           `json:"elapsed"`
+	Completed int             `json:"completed"`
+	Total     int             `json:"total"`
+	Errors    int             `json:"errors"`
+	QPS       float64         `json:"qps"`
+	P50       int64           `json:"p50"`
+	P99       int64           `json:"p99"`
+}
+
+// Daemon is the http.Handler of the daemon.
+type Daemon struct {
+	runner *Runner
+	dir    string
+}
+
+// NewDaemon returns a daemon running its jobs with runner. The files named by
+// the jobs are confined to dir, jobs cannot name any if it is empty.
+func NewDaemon(runner *Runner, dir string) *Daemon {
+	return &Daemon{runner: runner, dir: dir}
+}
+
+// rpcStream writes json lines, flushing each one so that progress reaches
+// the client as it happens.
+type rpcStream struct {
+	m sync.Mutex
+	w http.ResponseWriter
+}
+
+func (s *rpcStream) send(v interface{}) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	json.NewEncoder(s.w).Encode(v)
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (s *rpcStream) fail(id json.RawMessage, code int, err error) {
+	s.send(rpcResponse{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: err.Error()}})
+}
+
+func (d *Daemon) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "json-rpc requests must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	s := &rpcStream{w: w}
+
+	var call rpcRequest
+	if err := json.NewDecoder(req.Body).Decode(&call); err != nil {
+		s.fail(nil, rpcParseError, err)
+		return
+	}
+	if call.JSONRPC != "2.0" || call.Method == "" {
+		s.fail(call.ID, rpcInvalidRequest, errors.New("not a json-rpc 2.0 request"))
+		return
+	}
+
+	switch call.Method {
+	case "Benchmark.Ping":
+		s.send(rpcResponse{JSONRPC: "2.0", ID: call.ID, Result: map[string]string{"version": Version}})
+	case "Benchmark.Run":
+		d.run(req, s, call)
+	default:
+		s.fail(call.ID, rpcMethodNotFound, errors.Errorf("method %q not found", call.Method))
+	}
+}
+
+func (d *Daemon) run(req *http.Request, s *rpcStream, call rpcRequest) {
+	var job Job
+	if err := json.Unmarshal(call.Params, &job); err != nil {
+		s.fail(call.ID, rpcInvalidParams, err)
+		return
+	}
+	if err := job.confine(d.dir); err != nil {
+		s.fail(call.ID, rpcInvalidParams, err)
+		return
+	}
+	cfg := job.config()
+	if err := cfg.Validate(); err != nil {
+		s.fail(call.ID, rpcInvalidParams, err)
+		return
+	}
+	queries, err := job.queries(cfg)
+	if err != nil {
+		s.fail(call.ID, rpcInvalidParams, err)
+		return
+	}
+	cfg.Nq = len(queries)
+	cfg.OnProgress = func(p Progress) {
+		s.send(rpcNotification{JSONRPC: "2.0", Method: "Benchmark.Progress", Params: progressJSON{
+			ID:        call.ID,
+			Elapsed:   int64(p.Elapsed),
+			Completed: p.Completed,
+			Total:     p.Total,
+			Errors:    p.Errors,
+			QPS:       p.QueriesPerSecond,
+			P50:       int64(p.P50),
+			P99:       int64(p.P99),
+		}})
+	}
+
+	infof("running case %q: %d searches with %d workers", cfg.CaseName(), cfg.Total, cfg.Parallel)
+	r, err := d.runner.Run(req.Context(), cfg, queries, job.sinks()...)
+	if err != nil && !errors.Is(err, ErrAssertionsFailed) {
+		s.fail(call.ID, rpcRunFailed, err)
+		return
+	}
+	s.send(rpcResponse{JSONRPC: "2.0", ID: call.ID, Result: r.toJSON()})
+}
@@ -0,0 +1,120 @@
+package benchmark
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type rpcLine struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params progressJSON    `json:"params"`
+	Result *resultsJSON    `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+func call(t *testing.T, url, body string) []rpcLine {
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+
+	var lines []rpcLine
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var line rpcLine
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &line), scanner.Text())
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestDaemon(t *testing.T) {
+	milvus := newFakeMilvus(t, func(method string, _ []byte) ([]byte, error) {
+		if method == searchMethod {
+			time.Sleep(time.Millisecond)
+		}
+		return nil, nil
+	})
+	runner := NewRunner()
+	defer runner.Close()
+	dir := t.TempDir()
+	srv := httptest.NewServer(NewDaemon(runner, dir))
+	defer srv.Close()
+
+	job := `{"jsonrpc": "2.0", "id": 7, "method": "Benchmark.Run", "params": {
+		"origin": "` + milvus.addr + `", "name": "hnsw",
+		"search_params": {"collection_name": "test", "index_type": "HNSW", "params": {"ef": 64}, "limit": 10},
+		"queries": [[1, 2], [3, 4]], "parallel": 1, "total": 20, "progress_interval": 0.002,
+		"assertions": ["qps<0"], "history_file": "` + DefaultHistoryFile + `"}}`
+	lines := call(t, srv.URL, job)
+	assert.True(t, len(lines) > 1)
+	for _, line := range lines[:len(lines)-1] {
+		assert.Equal(t, "Benchmark.Progress", line.Method)
+		assert.Equal(t, "7", string(line.Params.ID))
+		assert.Equal(t, 20, line.Params.Total)
+	}
+	last := lines[len(lines)-1]
+	assert.Equal(t, "7", string(last.ID))
+	assert.Nil(t, last.Error)
+	assert.Equal(t, 20, last.Result.Metadata.Successful)
+	assert.Equal(t, 2, last.Result.Metadata.Run.Config.Nq)
+	assert.Equal(t, "hnsw", last.Result.Metadata.Run.Config.Name)
+	// failed assertions are part of the results
+	assert.False(t, last.Result.Assertions[0].Passed)
+	records, err := ReadHistory(filepath.Join(dir, DefaultHistoryFile))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(records))
+
+	// the connection to Milvus is kept for the next case
+	lines = call(t, srv.URL, strings.Replace(job, `"total": 20`, `"total": 5`, 1))
+	assert.Equal(t, 5, lines[len(lines)-1].Result.Metadata.Successful)
+	assert.Equal(t, 1, len(runner.clients))
+
+	lines = call(t, srv.URL, `{"jsonrpc": "2.0", "id": 1, "method": "Benchmark.Ping"}`)
+	assert.Equal(t, 1, len(lines))
+	assert.Nil(t, lines[0].Error)
+}
+
+func TestDaemon_errors(t *testing.T) {
+	srv := httptest.NewServer(NewDaemon(NewRunner(), t.TempDir()))
+	defer srv.Close()
+	job := `{"jsonrpc": "2.0", "id": 1, "method": "Benchmark.Run", "params": {"origin": "localhost:1",
+		"search_params": {"collection_name": "test"}, "queries": [[1, 2]]`
+
+	for body, code := range map[string]int{
+		`not json`:                             rpcParseError,
+		`{"id": 1, "method": "Benchmark.Run"}`: rpcInvalidRequest,
+		`{"jsonrpc": "2.0", "id": 1, "method": "Benchmark.Stop"}`:              rpcMethodNotFound,
+		`{"jsonrpc": "2.0", "id": 1, "method": "Benchmark.Run", "params": []}`: rpcInvalidParams,
+		`{"jsonrpc": "2.0", "id": 1, "method": "Benchmark.Run", "params": {}}`: rpcInvalidParams,
+		`{"jsonrpc": "2.0", "id": 1, "method": "Benchmark.Run", "params": {"origin": "localhost:1",
+			"search_params": {"collection_name": "test"}}}`: rpcInvalidParams,
+		job + `, "total": -1}}`:                         rpcInvalidParams,
+		job + `, "report": "/etc/out.html"}}`:           rpcInvalidParams,
+		job + `, "trace_out": "../trace.csv"}}`:         rpcInvalidParams,
+		job + `, "query_file": "a/../../queries.npy"}}`: rpcInvalidParams,
+	} {
+		lines := call(t, srv.URL, body)
+		assert.Equal(t, 1, len(lines), body)
+		assert.Equal(t, code, lines[0].Error.Code, body)
+	}
+
+	noDir := httptest.NewServer(NewDaemon(NewRunner(), ""))
+	defer noDir.Close()
+	lines := call(t, noDir.URL, job+`, "history_file": "history.jsonl"}}`)
+	assert.Equal(t, rpcInvalidParams, lines[0].Error.Code)
+
+	resp, err := http.Get(srv.URL)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
@@ -0,0 +1,387 @@
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/rpc"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// A distributed run has one coordinator, a Run with Config.Agents set, and
+// several agents. Agents register their address with the coordinator,
+// which then hands each of them a share of the workload over net/rpc. All
+// agents start at the same time and report their latencies back, which the
+// coordinator merges into a single Results.
+
+// startDelay is how far in the future the coordinator schedules the start,
+// it has to cover sending the job and the agents connecting to Milvus.
+var startDelay = 3 * time.Second
+
+// AgentJob is the share of a run assigned to one agent.
+type AgentJob struct {
+	Config  Config
+	Queries Queries
+}
+
+// AgentResult holds the latencies of one agent at microsecond resolution,
+// so that it stays small however many requests were sent.
+type AgentResult struct {
+	Latencies map[int64]uint64
+	Server    ServerInfo
+	Start     time.Time
+	Took      time.Duration
+	// ResultCheck is set if the search results were checked.
+	ResultCheck *ResultCheck
+	Payload     *PayloadStats
+	ClientLoad  *ClientLoad
+	// Retries is set if the job had a retry policy.
+	Retries   *retryRecord
+	ThinkTime *ThinkTimeStats
+}
+
+func newAgentResult(e execution) AgentResult {
+	out := AgentResult{
+		Latencies:   map[int64]uint64{},
+		Server:      e.server,
+		Start:       e.start,
+		Took:        e.took,
+		ResultCheck: e.check,
+		Payload:     e.payload,
+		ClientLoad:  e.load,
+		Retries:     e.retries,
+		ThinkTime:   e.think,
+	}
+	for _, t := range e.rec.latencies() {
+		out.Latencies[t.Microseconds()]++
+	}
+	return out
+}
+
+func (r AgentResult) times() []time.Duration {
+	return expandLatencies(r.Latencies)
+}
+
+func runAgentJob(job AgentJob) (AgentResult, error) {
+	ctx := context.Background()
+	runner := NewRunner()
+	defer runner.Close()
+	eps, err := endpoints(ctx, job.Config, runner.client)
+	if err != nil {
+		return AgentResult{}, err
+	}
+	e, err := execute(ctx, job.Config, eps, job.Queries)
+	if err != nil {
+		return AgentResult{}, err
+	}
+	return newAgentResult(e), nil
+}
+
+// Agent is the rpc service of an agent process.
+type Agent struct {
+	runJob func(AgentJob) (AgentResult, error)
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewAgent returns an agent running its jobs against Milvus.
+func NewAgent() *Agent {
+	return newAgent(runAgentJob)
+}
+
+func newAgent(runJob func(AgentJob) (AgentResult, error)) *Agent {
+	return &Agent{runJob: runJob, done: make(chan struct{})}
+}
+
+// Run executes a job.
+func (a *Agent) Run(job AgentJob, reply *AgentResult) error {
+	infof("agent running %d searches with %d workers", job.Config.Total, job.Config.Parallel)
+	res, err := a.runJob(job)
+	if err != nil {
+		return err
+	}
+	*reply = res
+	return nil
+}
+
+// Shutdown makes Serve return, the coordinator calls it once it got the
+// reply of Run or gave up on it. Its own reply may be lost as the agent
+// exits.
+func (a *Agent) Shutdown(_ bool, _ *bool) error {
+	a.once.Do(func() { close(a.done) })
+	return nil
+}
+
+// Serve registers with the coordinator and serves until it is shut down.
+func (a *Agent) Serve(listen, coordinator string) error {
+	srv := rpc.NewServer()
+	if err := srv.Register(a); err != nil {
+		return err
+	}
+	lis, err := net.Listen("tcp", listen)
+	if err != nil {
+		return err
+	}
+	defer lis.Close()
+	go srv.Accept(lis)
+
+	c, err := rpc.Dial("tcp", coordinator)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	var id int
+	if err := c.Call("Coordinator.Register", advertisedAddr(lis.Addr(), coordinator), &id); err != nil {
+		return err
+	}
+	infof("registered as agent %d with coordinator %s", id, coordinator)
+
+	<-a.done
+	return nil
+}
+
+// advertisedAddr replaces an unspecified listen host with the local address
+// used to reach the coordinator.
+func advertisedAddr(addr net.Addr, coordinator string) string {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok || !tcp.IP.IsUnspecified() {
+		return addr.String()
+	}
+	conn, err := net.Dial("udp", coordinator)
+	if err != nil {
+		return fmt.Sprintf("127.0.0.1:%d", tcp.Port)
+	}
+	defer conn.Close()
+	local := conn.LocalAddr().(*net.UDPAddr)
+	return net.JoinHostPort(local.IP.String(), fmt.Sprint(tcp.Port))
+}
+
+// Coordinator is the rpc service agents register with.
+type Coordinator struct {
+	m      sync.Mutex
+	agents []string
+	joined chan struct{}
+	want   int
+}
+
+func (c *Coordinator) Register(addr string, id *int) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if len(c.agents) >= c.want {
+		return errors.Errorf("all %d agents already registered", c.want)
+	}
+	*id = len(c.agents)
+	c.agents = append(c.agents, addr)
+	infof("agent %d registered from %s", *id, addr)
+	if len(c.agents) == c.want {
+		close(c.joined)
+	}
+	return nil
+}
+
+// splitShare returns the share of n assigned to the i-th of parts.
+func splitShare(n, parts, i int) int {
+	share := n / parts
+	if i < n%parts {
+		share++
+	}
+	return share
+}
+
+// agentJobs splits cfg across agents, every agent gets all the queries.
+func agentJobs(cfg Config, queries Queries, agents int, startAt time.Time) []AgentJob {
+	jobs := make([]AgentJob, agents)
+	for i := range jobs {
+		c := cfg
+		c.Total = splitShare(cfg.Total, agents, i)
+		c.Parallel = splitShare(cfg.Parallel, agents, i)
+		c.StartAt = startAt
+		c.Agents = 0
+		c.MetricsAddr = ""
+		// the workers of the agents draw from streams of their own
+		c.Seed = seedStream(cfg.Seed, "agent", i)
+		jobs[i] = AgentJob{Config: c, Queries: queries}
+	}
+	return jobs
+}
+
+// shutdownTimeout bounds the wait for an agent to take a Shutdown.
+const shutdownTimeout = time.Second
+
+// runAgents sends the agents their jobs and waits for their results. The
+// agents run equal shares from the same start, so once one of them answered
+// the others get cfg.AgentTimeout, or as long as the job of the answer took
+// if it succeeded and that is longer, before they count as failed. All
+// agents are shut down at the end.
+func runAgents(ctx context.Context, cfg Config, agents []string, jobs []AgentJob) ([]AgentResult, []error) {
+	results := make([]AgentResult, len(agents))
+	errs := make([]error, len(agents))
+	clients := make([]*rpc.Client, len(agents))
+	answers := make(chan *rpc.Call, len(agents))
+	pending := map[*rpc.Call]int{}
+	for i, addr := range agents {
+		c, err := rpc.Dial("tcp", addr)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		clients[i] = c
+		pending[c.Go("Agent.Run", jobs[i], &results[i], answers)] = i
+	}
+
+	var stragglers <-chan time.Time
+	// succeeded is set once the timer was armed by a successful answer, which
+	// tells how long a job takes
+	var succeeded bool
+	arm := func(took time.Duration, success bool) {
+		if succeeded || (stragglers != nil && !success) {
+			return
+		}
+		if took < cfg.AgentTimeout {
+			took = cfg.AgentTimeout
+		}
+		stragglers = time.After(took)
+		succeeded = success
+	}
+	if len(pending) < len(agents) {
+		// an agent could not be reached
+		arm(0, false)
+	}
+	var giveUp error
+	for len(pending) > 0 && giveUp == nil {
+		select {
+		case call := <-answers:
+			i := pending[call]
+			delete(pending, call)
+			errs[i] = call.Error
+			arm(results[i].Took, call.Error == nil)
+		case <-stragglers:
+			giveUp = errors.New("no result long after the other agents")
+		case <-ctx.Done():
+			giveUp = ctx.Err()
+		}
+	}
+	for _, i := range pending {
+		errs[i] = giveUp
+	}
+
+	for _, c := range clients {
+		if c == nil {
+			continue
+		}
+		select {
+		case <-c.Go("Agent.Shutdown", true, new(bool), nil).Done:
+		case <-time.After(shutdownTimeout):
+		}
+		c.Close()
+	}
+	return results, errs
+}
+
+// coordinate waits for cfg.Agents agents, runs the workload on them and
+// merges their results. Requests of failed agents are counted as failed.
+func coordinate(ctx context.Context, cfg Config, queries Queries) (Results, error) {
+	lis, err := net.Listen("tcp", cfg.CoordinatorAddr)
+	if err != nil {
+		return Results{}, err
+	}
+	defer lis.Close()
+	infof("waiting for %d agents on %s", cfg.Agents, lis.Addr())
+	return coordinateOn(ctx, lis, cfg, queries)
+}
+
+func coordinateOn(ctx context.Context, lis net.Listener, cfg Config, queries Queries) (Results, error) {
+	coord := &Coordinator{joined: make(chan struct{}), want: cfg.Agents}
+	srv := rpc.NewServer()
+	if err := srv.Register(coord); err != nil {
+		return Results{}, err
+	}
+	go srv.Accept(lis)
+
+	select {
+	case <-coord.joined:
+	case <-ctx.Done():
+		return Results{}, ctx.Err()
+	case <-time.After(cfg.AgentTimeout):
+		coord.m.Lock()
+		defer coord.m.Unlock()
+		return Results{}, errors.Errorf("only %d of %d agents registered within %s",
+			len(coord.agents), cfg.Agents, cfg.AgentTimeout)
+	}
+
+	startAt := time.Now().Add(startDelay)
+	jobs := agentJobs(cfg, queries, cfg.Agents, startAt)
+	results, errs := runAgents(ctx, cfg, coord.agents, jobs)
+
+	var failed []string
+	var times []time.Duration
+	var server ServerInfo
+	var check *ResultCheck
+	var payloads []*PayloadStats
+	var load *ClientLoad
+	var retries *retryRecord
+	var think *ThinkTimeStats
+	end := startAt
+	for i := range results {
+		if errs[i] != nil {
+			failed = append(failed, fmt.Sprintf("agent %d (%s): %s", i, coord.agents[i], errs[i]))
+			continue
+		}
+		times = append(times, results[i].times()...)
+		if finished := results[i].Start.Add(results[i].Took); finished.After(end) {
+			end = finished
+		}
+		server = results[i].Server
+		if results[i].ResultCheck != nil {
+			if check == nil {
+				check = &ResultCheck{}
+			}
+			check.merge(results[i].ResultCheck)
+		}
+		if results[i].Payload != nil {
+			payloads = append(payloads, results[i].Payload)
+		}
+		if results[i].ClientLoad != nil {
+			if load == nil {
+				load = &ClientLoad{}
+			}
+			load.merge(results[i].ClientLoad)
+		}
+		if results[i].Retries != nil {
+			if retries == nil {
+				retries = &retryRecord{}
+			}
+			retries.merge(results[i].Retries)
+		}
+		if results[i].ThinkTime != nil {
+			if think == nil {
+				think = &ThinkTimeStats{}
+			}
+			think.merge(results[i].ThinkTime)
+		}
+	}
+	if len(failed) == len(results) {
+		return Results{}, errors.Errorf("all agents failed:\n%s", strings.Join(failed, "\n"))
+	}
+	for _, f := range failed {
+		fmt.Fprintf(output, "%s%s%s\n", colorRed, f, colorReset)
+	}
+
+	out := analyze(cfg, times, end.Sub(startAt))
+	out.Run = newRunInfo(cfg, server, startAt, end)
+	out.ResultCheck = check
+	out.ClientLoad = load
+	out.Retries = retries.stats(cfg, out.PercentilesLabels)
+	out.ThinkTime = think
+	if len(payloads) > 0 {
+		out.Payload = &PayloadStats{}
+		for _, p := range payloads {
+			out.Payload.merge(p, out.Took)
+		}
+	}
+	return out, nil
+}
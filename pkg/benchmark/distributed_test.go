@@ -0,0 +1,132 @@
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAgentJobs(t *testing.T) {
+	cfg := Config{Total: 10, Parallel: 5, Agents: 3, MetricsAddr: ":9100"}
+	startAt := time.Now()
+	jobs := agentJobs(cfg, Queries{{1, 2}}, 3, startAt)
+
+	totals, parallels := 0, 0
+	for _, job := range jobs {
+		totals += job.Config.Total
+		parallels += job.Config.Parallel
+		assert.Equal(t, startAt, job.Config.StartAt)
+		assert.Equal(t, 0, job.Config.Agents)
+		assert.Equal(t, "", job.Config.MetricsAddr)
+		assert.Equal(t, Queries{{1, 2}}, job.Queries)
+	}
+	assert.Equal(t, 10, totals)
+	assert.Equal(t, 5, parallels)
+	assert.Equal(t, []int{4, 3, 3}, []int{jobs[0].Config.Total, jobs[1].Config.Total, jobs[2].Config.Total})
+}
+
+func TestCoordinate(t *testing.T) {
+	defer func(d time.Duration) { startDelay = d }(startDelay)
+	startDelay = 100 * time.Millisecond
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer lis.Close()
+
+	fake := func(job AgentJob) (AgentResult, error) {
+		time.Sleep(time.Until(job.Config.StartAt))
+		res := AgentResult{Latencies: map[int64]uint64{}, Start: time.Now(), Took: 10 * time.Millisecond}
+		res.Latencies[1000] = uint64(job.Config.Total)
+		return res, nil
+	}
+	broken := func(job AgentJob) (AgentResult, error) {
+		return AgentResult{}, errors.New("lost connection to Milvus")
+	}
+	for _, run := range []func(AgentJob) (AgentResult, error){fake, fake, broken} {
+		go func(run func(AgentJob) (AgentResult, error)) {
+			assert.Nil(t, newAgent(run).Serve("127.0.0.1:0", lis.Addr().String()))
+		}(run)
+	}
+
+	cfg := Config{Total: 30, Parallel: 3, Agents: 3, AgentTimeout: 5 * time.Second}
+	r, err := coordinateOn(context.Background(), lis, cfg, Queries{{1}})
+	assert.Nil(t, err)
+	assert.Equal(t, 30, r.Total)
+	// the broken agent is one of the three, each was assigned 10 searches
+	assert.Equal(t, 20, r.Successful)
+	assert.Equal(t, 10, r.Failed)
+	assert.Equal(t, time.Millisecond, r.Max)
+	assert.True(t, r.Took >= 10*time.Millisecond)
+}
+
+func TestCoordinate_timeout(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer lis.Close()
+
+	_, err = coordinateOn(context.Background(), lis, Config{Total: 2, Parallel: 2, Agents: 2, AgentTimeout: 10 * time.Millisecond}, nil)
+	assert.Error(t, err)
+}
+
+func TestCoordinate_cancel(t *testing.T) {
+	defer func(d time.Duration) { startDelay = d }(startDelay)
+	startDelay = 0
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer lis.Close()
+
+	release := make(chan struct{})
+	defer close(release)
+	hanging := func(job AgentJob) (AgentResult, error) {
+		<-release
+		return AgentResult{}, nil
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- newAgent(hanging).Serve("127.0.0.1:0", lis.Addr().String())
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = coordinateOn(ctx, lis, Config{Total: 2, Parallel: 1, Agents: 1, AgentTimeout: 5 * time.Second}, Queries{{1}})
+	assert.Error(t, err)
+	// the agent was shut down although its job never finished
+	select {
+	case err := <-served:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent still serving")
+	}
+}
+
+func TestCoordinate_hangingAfterFailure(t *testing.T) {
+	defer func(d time.Duration) { startDelay = d }(startDelay)
+	startDelay = 0
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer lis.Close()
+
+	release := make(chan struct{})
+	defer close(release)
+	hanging := func(job AgentJob) (AgentResult, error) {
+		<-release
+		return AgentResult{}, nil
+	}
+	broken := func(job AgentJob) (AgentResult, error) {
+		return AgentResult{}, errors.New("lost connection to Milvus")
+	}
+	for _, run := range []func(AgentJob) (AgentResult, error){hanging, broken} {
+		go newAgent(run).Serve("127.0.0.1:0", lis.Addr().String())
+	}
+
+	// the only answer is a failure, the hanging agent is still given up on
+	cfg := Config{Total: 2, Parallel: 2, Agents: 2, AgentTimeout: 500 * time.Millisecond}
+	_, err = coordinateOn(context.Background(), lis, cfg, Queries{{1}})
+	assert.Error(t, err)
+}
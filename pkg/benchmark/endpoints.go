@@ -0,0 +1,178 @@
+package benchmark
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	milvusClient "github.com/xiaocai2333/milvus-sdk-go/v2/client"
+)
+
+// Balancing policies of Config.Balance, used when Origin lists several
+// endpoints.
+const (
+	BalanceRoundRobin    = "round-robin"
+	BalanceRandom        = "random"
+	BalanceLeastInflight = "least-inflight"
+)
+
+// Balancing scopes of Config.BalanceScope: an endpoint is picked for every
+// request, or once per worker which then sends all its requests to it.
+const (
+	BalancePerRequest = "request"
+	BalancePerWorker  = "worker"
+)
+
+// origins returns the endpoints of Origin, a comma separated list.
+func (c Config) origins() []string {
+	var out []string
+	for _, origin := range strings.Split(c.Origin, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			out = append(out, origin)
+		}
+	}
+	return out
+}
+
+func (c Config) validateBalance() error {
+	switch c.Balance {
+	case "", BalanceRoundRobin, BalanceRandom, BalanceLeastInflight:
+	default:
+		return errors.Errorf("unsupported balance %q, must be one of [%s, %s, %s]",
+			c.Balance, BalanceRoundRobin, BalanceRandom, BalanceLeastInflight)
+	}
+	switch c.BalanceScope {
+	case "", BalancePerRequest, BalancePerWorker:
+	default:
+		return errors.Errorf("unsupported balance scope %q, must be one of [%s, %s]",
+			c.BalanceScope, BalancePerRequest, BalancePerWorker)
+	}
+	// the endpoints are recorded by origin, a duplicate would share the
+	// record of the first
+	seen := map[string]bool{}
+	for _, origin := range c.origins() {
+		if seen[origin] {
+			return errors.Errorf("duplicate origin %q", origin)
+		}
+		seen[origin] = true
+	}
+	return nil
+}
+
+type endpoint struct {
+	origin string
+	client milvusClient.Client
+}
+
+// endpoints connects to every endpoint of cfg.Origin with client.
+func endpoints(ctx context.Context, cfg Config,
+	client func(context.Context, string, Connection) (milvusClient.Client, error)) ([]endpoint, error) {
+	var out []endpoint
+	for _, origin := range cfg.origins() {
+		c, err := client(ctx, origin, cfg.Connection)
+		if err != nil {
+			return nil, errors.Wrapf(err, "connect to %s", origin)
+		}
+		out = append(out, endpoint{origin: origin, client: c})
+	}
+	return out, nil
+}
+
+// balancer picks the endpoint of the next request, every pick must be
+// followed by done once the request completed.
+type balancer struct {
+	policy string
+
+	m        sync.Mutex
+	next     int
+	inflight []int
+}
+
+func newBalancer(policy string, n int) *balancer {
+	return &balancer{
+		policy:   policy,
+		inflight: make([]int, n),
+	}
+}
+
+// pick returns the endpoint of the next request, a random one is drawn from
+// the stream of the worker asking so that its requests are reproducible.
+func (b *balancer) pick(r *rand.Rand) int {
+	b.m.Lock()
+	defer b.m.Unlock()
+	var i int
+	switch b.policy {
+	case BalanceRandom:
+		i = r.Intn(len(b.inflight))
+	case BalanceLeastInflight:
+		// ties go round-robin so that an idle cluster is still spread
+		for j := range b.inflight {
+			k := (b.next + j) % len(b.inflight)
+			if b.inflight[k] < b.inflight[i] || j == 0 {
+				i = k
+			}
+		}
+		b.next = (i + 1) % len(b.inflight)
+	default:
+		i = b.next
+		b.next = (b.next + 1) % len(b.inflight)
+	}
+	b.inflight[i]++
+	return i
+}
+
+func (b *balancer) done(i int) {
+	b.m.Lock()
+	b.inflight[i]--
+	b.m.Unlock()
+}
+
+// EndpointStats is the share of one endpoint in a run across several.
+type EndpointStats struct {
+	Origin string
+	// Successful and Failed count the attempts sent to the endpoint, a
+	// retried request counts on every endpoint it went to. The latencies
+	// are those of the attempts.
+	Successful       int
+	Failed           int
+	QueriesPerSecond float64
+	Mean             time.Duration
+	Max              time.Duration
+	// Percentiles are those of Results.PercentilesLabels.
+	Percentiles []time.Duration
+}
+
+// endpointStats breaks the run down by endpoint, it is empty unless the run
+// went to several.
+func (r *recorder) endpointStats(cfg Config, origins []string, took time.Duration) []EndpointStats {
+	if len(origins) < 2 {
+		return nil
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	out := make([]EndpointStats, 0, len(origins))
+	for _, origin := range origins {
+		var times []time.Duration
+		var failed int
+		if e, ok := r.endpoints[origin]; ok {
+			times = append(times, e.times...)
+			failed = e.errors
+		}
+		share := cfg
+		share.Total = len(times) + failed
+		a := analyze(share, times, took)
+		out = append(out, EndpointStats{
+			Origin:           origin,
+			Successful:       a.Successful,
+			Failed:           a.Failed,
+			QueriesPerSecond: a.QueriesPerSecond,
+			Mean:             a.Mean,
+			Max:              a.Max,
+			Percentiles:      a.Percentiles,
+		})
+	}
+	return out
+}
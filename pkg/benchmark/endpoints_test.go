@@ -0,0 +1,85 @@
+package benchmark
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestConfigOrigins(t *testing.T) {
+	assert.Equal(t, []string{"a:19530"}, Config{Origin: "a:19530"}.origins())
+	assert.Equal(t, []string{"a:19530", "b:19530"}, Config{Origin: "a:19530, b:19530,"}.origins())
+	assert.Nil(t, Config{Origin: " , "}.origins())
+	assert.Error(t, Config{Origin: "a:19530, a:19530"}.validateBalance())
+	assert.Nil(t, Config{Origin: "a:19530,b:19530"}.validateBalance())
+}
+
+func TestBalancer(t *testing.T) {
+	b := newBalancer(BalanceRoundRobin, 3)
+	var picks []int
+	for i := 0; i < 4; i++ {
+		picks = append(picks, b.pick(nil))
+	}
+	assert.Equal(t, []int{0, 1, 2, 0}, picks)
+
+	b = newBalancer(BalanceLeastInflight, 3)
+	assert.Equal(t, 0, b.pick(nil))
+	assert.Equal(t, 1, b.pick(nil))
+	assert.Equal(t, 2, b.pick(nil))
+	b.done(1)
+	assert.Equal(t, 1, b.pick(nil))
+	b.done(2)
+	b.done(0)
+	assert.Equal(t, 2, b.pick(nil))
+	assert.Equal(t, 0, b.pick(nil))
+
+	b = newBalancer(BalanceRandom, 3)
+	seen := map[int]bool{}
+	rng := newStream(1, "worker", 0)
+	for i := 0; i < 100; i++ {
+		seen[b.pick(rng)] = true
+	}
+	assert.Equal(t, 3, len(seen))
+}
+
+func TestRun_endpoints(t *testing.T) {
+	a := newFakeMilvus(t, nil)
+	b := newFakeMilvus(t, func(method string, _ []byte) ([]byte, error) {
+		if method == searchMethod {
+			return nil, status.Error(codes.Unavailable, "proxy is down")
+		}
+		return nil, nil
+	})
+
+	cfg := testConfig(a.addr + "," + newFakeMilvus(t, nil).addr)
+	cfg.BalanceScope = BalancePerWorker
+	r, err := Run(context.Background(), cfg, Queries{{1, 2}})
+	assert.Nil(t, err)
+	assert.Equal(t, 5, a.count(searchMethod))
+	assert.Equal(t, 2, len(r.Endpoints))
+	for _, e := range r.Endpoints {
+		assert.Equal(t, 5, e.Successful)
+		assert.Equal(t, len(r.PercentilesLabels), len(e.Percentiles))
+	}
+	assert.Equal(t, BalanceRoundRobin, r.Run.toJSON().Config.Balance)
+	text := &strings.Builder{}
+	_, err = r.WriteTextTo(text)
+	assert.Nil(t, err)
+	assert.Contains(t, text.String(), "Endpoints\n"+a.addr+": successful 5, failed 0")
+
+	cfg.Origin = a.addr + "," + b.addr
+	cfg.Parallel = 1
+	cfg.BalanceScope = BalancePerRequest
+	_, err = Run(context.Background(), cfg, Queries{{1, 2}})
+	assert.Error(t, err)
+	assert.Equal(t, 6, a.count(searchMethod))
+	assert.Equal(t, 1, b.count(searchMethod))
+
+	cfg.Balance = "fastest"
+	_, err = Run(context.Background(), cfg, Queries{{1, 2}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,69 @@
+package benchmark
+
+import (
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// fakeMilvus is a grpc stand-in for Milvus. It answers every rpc with the
+// response returned by handle, noopResponse if handle is nil or returns nil.
+type fakeMilvus struct {
+	addr string
+
+	m             sync.Mutex
+	calls         map[string]int
+	authorization []string
+}
+
+func newFakeMilvus(t *testing.T, handle func(method string, req []byte) ([]byte, error), opts ...grpc.ServerOption) *fakeMilvus {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	f := &fakeMilvus{addr: lis.Addr().String(), calls: map[string]int{}}
+	opts = append(opts, grpc.CustomCodec(rawCodec{}), grpc.UnknownServiceHandler(
+		func(_ interface{}, stream grpc.ServerStream) error {
+			method, _ := grpc.MethodFromServerStream(stream)
+			f.m.Lock()
+			f.calls[method]++
+			if md, ok := metadata.FromIncomingContext(stream.Context()); ok {
+				f.authorization = md.Get("authorization")
+			}
+			f.m.Unlock()
+			var req []byte
+			if err := stream.RecvMsg(&req); err != nil {
+				return err
+			}
+			var resp []byte
+			if handle != nil {
+				var err error
+				if resp, err = handle(method, req); err != nil {
+					return err
+				}
+			}
+			if resp == nil {
+				resp = noopResponse
+			}
+			return stream.SendMsg(&resp)
+		}))
+	srv := grpc.NewServer(opts...)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return f
+}
+
+func (f *fakeMilvus) count(method string) int {
+	f.m.Lock()
+	defer f.m.Unlock()
+	return f.calls[method]
+}
+
+// lastAuthorization returns the authorization metadata of the last call.
+func (f *fakeMilvus) lastAuthorization() []string {
+	f.m.Lock()
+	defer f.m.Unlock()
+	return f.authorization
+}
@@ -0,0 +1,222 @@
+package benchmark
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultHistoryFile is the conventional name of a history file.
+const DefaultHistoryFile = "benchmark-history.jsonl"
+
+// outlierScore is the robust z-score above which a run is flagged, see
+// https://www.itl.nist.gov/div898/handbook/eda/section3/eda35h.htm
+const outlierScore = 3.5
+
+// HistoryRecord is one line of a history file.
+type HistoryRecord struct {
+	Name       string      `json:"name"`
+	Collection string      `json:"collection"`
+	Timestamp  time.Time   `json:"timestamp"`
+	Results    resultsJSON `json:"results"`
+}
+
+// CaseName identifies the case of a run in the history, unless Name is set
+// it is derived from the search params.
+func (c Config) CaseName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	name := fmt.Sprintf("%s_%s_ef%d_top%d_nq%d_p%d",
+		c.IndexType, c.MetricType, c.Params.Ef, c.Limit, c.Nq, c.Parallel)
+	// cases with the default consistency keep the names they always had
+	if level, err := c.consistencyLevel(); err == nil && level != DefaultConsistencyLevel {
+		name += "_" + level
+	}
+	return name
+}
+
+// appendHistory appends r as a single json line to fname.
+func appendHistory(fname string, cfg Config, r Results) error {
+	timestamp := r.Run.StartedAt
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	line, err := json.Marshal(HistoryRecord{
+		Name:       cfg.CaseName(),
+		Collection: cfg.CollectionName,
+		Timestamp:  timestamp,
+		Results:    r.toJSON(),
+	})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(fname, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// ReadHistory returns the records of fname ordered by timestamp.
+func ReadHistory(fname string) ([]HistoryRecord, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []HistoryRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var rec HistoryRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			return nil, errors.Wrapf(err, "%s:%d", fname, lineNo)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(records, func(a, b int) bool {
+		return records[a].Timestamp.Before(records[b].Timestamp)
+	})
+	return records, nil
+}
+
+// FilterHistory returns the records of the named case against collection,
+// an empty name or collection matches any.
+func FilterHistory(records []HistoryRecord, name, collection string) []HistoryRecord {
+	var out []HistoryRecord
+	for _, rec := range records {
+		if name != "" && rec.Name != name {
+			continue
+		}
+		if collection != "" && rec.Collection != collection {
+			continue
+		}
+		out = append(out, rec)
+	}
+	return out
+}
+
+// p99 returns the p99 latency of rec, runs with other percentiles do not
+// have one.
+func (rec HistoryRecord) p99() (time.Duration, bool) {
+	p99, ok := rec.Results.Latencies["p99"]
+	return time.Duration(p99), ok
+}
+
+// WriteHistoryTo lists records, one line per run.
+func WriteHistoryTo(w io.Writer, records []HistoryRecord) (int, error) {
+	b := strings.Builder{}
+	b.WriteString(fmt.Sprintf("%-20s %-20s %-36s %12s %12s %8s\n",
+		"timestamp", "collection", "case", "qps", "p99", "failed"))
+	for _, rec := range records {
+		p99 := "n/a"
+		if d, ok := rec.p99(); ok {
+			p99 = d.String()
+		}
+		b.WriteString(fmt.Sprintf("%-20s %-20s %-36s %12.3f %12s %8d\n",
+			rec.Timestamp.Local().Format("2006-01-02 15:04:05"), rec.Collection, rec.Name,
+			rec.Results.Throughput.QPS, p99, rec.Results.Metadata.Failed))
+	}
+	return w.Write([]byte(b.String()))
+}
+
+// outliers flags the values whose robust z-score, based on the median
+// absolute deviation, exceeds outlierScore.
+func outliers(values []float64) []bool {
+	flags := make([]bool, len(values))
+	if len(values) < 3 {
+		return flags
+	}
+	med := median(values)
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - med)
+	}
+	mad := median(deviations)
+	if mad == 0 {
+		return flags
+	}
+	for i, d := range deviations {
+		flags[i] = 0.6745*d/mad > outlierScore
+	}
+	return flags
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// WriteTrendTo prints qps and p99 of every run with the change against the
+// previous run, outliers are marked with "!". The p99 of a run without one is
+// shown as n/a, its change is against the previous run that has one.
+func WriteTrendTo(w io.Writer, records []HistoryRecord) (int, error) {
+	qps := make([]float64, len(records))
+	var p99 []float64
+	// p99Index[i] is the index of the p99 of records[i] in p99, -1 if missing
+	p99Index := make([]int, len(records))
+	for i, rec := range records {
+		qps[i] = rec.Results.Throughput.QPS
+		p99Index[i] = -1
+		if d, ok := rec.p99(); ok {
+			p99Index[i] = len(p99)
+			p99 = append(p99, float64(d))
+		}
+	}
+	qpsOutliers, p99Outliers := outliers(qps), outliers(p99)
+
+	mark := func(outlier bool) string {
+		if outlier {
+			return "!"
+		}
+		return " "
+	}
+	b := strings.Builder{}
+	b.WriteString(fmt.Sprintf("%-20s %12s %9s  %12s %9s\n", "timestamp", "qps", "change", "p99", "change"))
+	for i, rec := range records {
+		qpsChange := 0.0
+		if i > 0 {
+			qpsChange = relativeChange(qps[i-1], qps[i])
+		}
+		b.WriteString(fmt.Sprintf("%-20s %12.3f %+8.2f%%%s ",
+			rec.Timestamp.Local().Format("2006-01-02 15:04:05"),
+			qps[i], qpsChange*100, mark(qpsOutliers[i])))
+		j := p99Index[i]
+		if j < 0 {
+			b.WriteString(fmt.Sprintf("%12s\n", "n/a"))
+			continue
+		}
+		p99Change := 0.0
+		if j > 0 {
+			p99Change = relativeChange(p99[j-1], p99[j])
+		}
+		b.WriteString(fmt.Sprintf("%12s %+8.2f%%%s\n",
+			time.Duration(p99[j]), p99Change*100, mark(p99Outliers[j])))
+	}
+	return w.Write([]byte(b.String()))
+}
@@ -0,0 +1,80 @@
+package benchmark
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), DefaultHistoryFile)
+	cfg := Config{Name: "hnsw_top10"}
+	cfg.CollectionName = "sift"
+
+	started := time.Date(2022, 7, 11, 0, 0, 0, 0, time.UTC)
+	qps := []float64{1000, 1010, 990, 400, 1005}
+	// append out of order, the history is sorted by timestamp when read
+	for _, i := range []int{4, 0, 1, 2, 3} {
+		r := testResults()
+		r.QueriesPerSecond = qps[i]
+		r.Run = newRunInfo(cfg, ServerInfo{}, started.Add(time.Duration(i)*time.Hour), started)
+		assert.Nil(t, appendHistory(fname, cfg, r))
+	}
+	other := cfg
+	other.Name = ""
+	assert.Nil(t, appendHistory(fname, other, testResults()))
+
+	records, err := ReadHistory(fname)
+	assert.Nil(t, err)
+	assert.Equal(t, 6, len(records))
+
+	records = FilterHistory(records, "hnsw_top10", "sift")
+	assert.Equal(t, 5, len(records))
+	for i, rec := range records {
+		assert.Equal(t, qps[i], rec.Results.Throughput.QPS)
+		assert.Equal(t, "sift", rec.Collection)
+	}
+	assert.Equal(t, 0, len(FilterHistory(records, "", "glove")))
+
+	b := &strings.Builder{}
+	_, err = WriteTrendTo(b, records)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	assert.Equal(t, 6, len(lines))
+	assert.Contains(t, lines[4], "400.000")
+	assert.Contains(t, lines[4], "%!")
+	assert.NotContains(t, lines[5], "%!")
+
+	delete(records[2].Results.Latencies, "p99")
+	b.Reset()
+	_, err = WriteTrendTo(b, records)
+	assert.Nil(t, err)
+	lines = strings.Split(strings.TrimSpace(b.String()), "\n")
+	assert.True(t, strings.HasSuffix(lines[3], " n/a"), lines[3])
+
+	b.Reset()
+	_, err = WriteHistoryTo(b, records)
+	assert.Nil(t, err)
+	assert.Contains(t, b.String(), "hnsw_top10")
+	assert.Contains(t, b.String(), " n/a ")
+}
+
+func TestHistory_invalid(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), DefaultHistoryFile)
+	_, err := ReadHistory(fname)
+	assert.Error(t, err)
+
+	assert.Nil(t, os.WriteFile(fname, []byte("{}\nnot json\n"), 0644))
+	_, err = ReadHistory(fname)
+	assert.Error(t, err)
+}
+
+func TestOutliers(t *testing.T) {
+	assert.Equal(t, []bool{false, false}, outliers([]float64{1, 100}))
+	assert.Equal(t, []bool{false, false, false}, outliers([]float64{5, 5, 5}))
+	assert.Equal(t, []bool{false, false, false, true, false}, outliers([]float64{10, 11, 9, 30, 10}))
+}
@@ -0,0 +1,172 @@
+package benchmark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metricsServer exposes the recorder of a running benchmark over HTTP:
+// /metrics in the Prometheus text format and /status as json.
+type metricsServer struct {
+	cfg   Config
+	rec   *recorder
+	start time.Time
+	srv   *http.Server
+}
+
+func newMetricsServer(cfg Config, rec *recorder, start time.Time) *metricsServer {
+	s := &metricsServer{cfg: cfg, rec: rec, start: start}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("/status", s.handleStatus)
+	s.srv = &http.Server{Handler: mux}
+	return s
+}
+
+// startMetricsServer serves on addr until Stop is called, it returns nil if
+// addr is empty.
+func startMetricsServer(addr string, cfg Config, rec *recorder, start time.Time) (*metricsServer, error) {
+	if addr == "" {
+		return nil, nil
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := newMetricsServer(cfg, rec, start)
+	go s.srv.Serve(lis)
+	infof("serving metrics on http://%s/metrics", lis.Addr())
+	return s, nil
+}
+
+func (s *metricsServer) Stop() {
+	if s == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	s.srv.Shutdown(ctx)
+}
+
+func (s *metricsServer) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	writeMetrics(w, s.rec.metrics())
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'g', -1, 64)
+}
+
+func writeMetrics(w io.Writer, m recorderMetrics) (int, error) {
+	b := strings.Builder{}
+
+	b.WriteString("# HELP benchmarker_requests_total Requests sent to Milvus by operation and grpc status.\n")
+	b.WriteString("# TYPE benchmarker_requests_total counter\n")
+	keys := make([]requestKey, 0, len(m.Requests))
+	for k := range m.Requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		if keys[a].Op != keys[b].Op {
+			return keys[a].Op < keys[b].Op
+		}
+		return keys[a].Status < keys[b].Status
+	})
+	for _, k := range keys {
+		b.WriteString(fmt.Sprintf("benchmarker_requests_total{operation=%q,status=%q} %d\n",
+			k.Op, k.Status, m.Requests[k]))
+	}
+
+	b.WriteString("# HELP benchmarker_retries_total Failed attempts retried by operation and grpc status.\n")
+	b.WriteString("# TYPE benchmarker_retries_total counter\n")
+	keys = keys[:0]
+	for k := range m.Retries {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		if keys[a].Op != keys[b].Op {
+			return keys[a].Op < keys[b].Op
+		}
+		return keys[a].Status < keys[b].Status
+	})
+	for _, k := range keys {
+		b.WriteString(fmt.Sprintf("benchmarker_retries_total{operation=%q,status=%q} %d\n",
+			k.Op, k.Status, m.Retries[k]))
+	}
+
+	b.WriteString("# HELP benchmarker_request_duration_seconds Client observed latency of successful requests.\n")
+	b.WriteString("# TYPE benchmarker_request_duration_seconds histogram\n")
+	ops := make([]string, 0, len(m.Histograms))
+	for op := range m.Histograms {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	for _, op := range ops {
+		h := m.Histograms[op]
+		var cumulative uint64
+		for i, le := range latencyBuckets {
+			cumulative += h.Counts[i]
+			b.WriteString(fmt.Sprintf("benchmarker_request_duration_seconds_bucket{operation=%q,le=%q} %d\n",
+				op, formatSeconds(le), cumulative))
+		}
+		b.WriteString(fmt.Sprintf("benchmarker_request_duration_seconds_bucket{operation=%q,le=\"+Inf\"} %d\n", op, h.Count))
+		b.WriteString(fmt.Sprintf("benchmarker_request_duration_seconds_sum{operation=%q} %s\n", op, formatSeconds(h.Sum)))
+		b.WriteString(fmt.Sprintf("benchmarker_request_duration_seconds_count{operation=%q} %d\n", op, h.Count))
+	}
+
+	b.WriteString("# HELP benchmarker_requests_in_flight Requests sent to Milvus and not answered yet.\n")
+	b.WriteString("# TYPE benchmarker_requests_in_flight gauge\n")
+	ops = ops[:0]
+	for op := range m.Inflight {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	for _, op := range ops {
+		b.WriteString(fmt.Sprintf("benchmarker_requests_in_flight{operation=%q} %d\n", op, m.Inflight[op]))
+	}
+	return w.Write([]byte(b.String()))
+}
+
+type statusJSON struct {
+	Config       resultsJSONConfig  `json:"config"`
+	SearchParams SearchParams       `json:"search_params"`
+	Progress     statusJSONProgress `json:"progress"`
+}
+
+type statusJSONProgress struct {
+	StartedAt time.Time `json:"started_at"`
+	Elapsed   string    `json:"elapsed"`
+	Completed int       `json:"completed"`
+	Total     int       `json:"total"`
+	Errors    int       `json:"errors"`
+	Inflight  int       `json:"in_flight"`
+}
+
+func (s *metricsServer) handleStatus(w http.ResponseWriter, _ *http.Request) {
+	m := s.rec.metrics()
+	run := RunInfo{Config: s.cfg, StartedAt: s.start}.toJSON()
+	obj := statusJSON{
+		Config:       run.Config,
+		SearchParams: run.SearchParams,
+		Progress: statusJSONProgress{
+			StartedAt: s.start,
+			Elapsed:   fmt.Sprint(time.Since(s.start).Truncate(time.Millisecond)),
+			Completed: m.Completed,
+			Total:     s.cfg.Total,
+			Errors:    m.Errors,
+		},
+	}
+	for _, v := range m.Inflight {
+		obj.Progress.Inflight += v
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(obj)
+}
@@ -0,0 +1,62 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsServer(t *testing.T) {
+	rec := newRecorder(10)
+	rec.begin(opSearch)
+	rec.record(opSearch, 3*time.Millisecond, nil)
+	rec.begin(opSearch)
+	rec.record(opSearch, 2*time.Second, nil)
+	rec.begin(opSearch)
+	rec.retry(opSearch, errors.New("proxy restarted"))
+	rec.record(opSearch, time.Millisecond, errors.New("proxy restarted"))
+	rec.begin(opSearch)
+
+	cfg := Config{Mode: "locust", Total: 10, QueryFile: "[[1]]"}
+	cfg.CollectionName = "test"
+	s := newMetricsServer(cfg, rec, time.Now())
+
+	w := httptest.NewRecorder()
+	s.handleMetrics(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	assert.Contains(t, body, `benchmarker_requests_total{operation="search",status="OK"} 2`)
+	assert.Contains(t, body, `benchmarker_requests_total{operation="search",status="Unknown"} 1`)
+	assert.Contains(t, body, `benchmarker_retries_total{operation="search",status="Unknown"} 1`)
+	assert.Contains(t, body, `benchmarker_request_duration_seconds_bucket{operation="search",le="0.0025"} 0`)
+	assert.Contains(t, body, `benchmarker_request_duration_seconds_bucket{operation="search",le="0.005"} 1`)
+	assert.Contains(t, body, `benchmarker_request_duration_seconds_bucket{operation="search",le="+Inf"} 2`)
+	assert.Contains(t, body, `benchmarker_request_duration_seconds_sum{operation="search"} 2.003`)
+	assert.Contains(t, body, `benchmarker_requests_in_flight{operation="search"} 1`)
+	assert.True(t, strings.HasSuffix(body, "\n"))
+
+	w = httptest.NewRecorder()
+	s.handleStatus(w, httptest.NewRequest("GET", "/status", nil))
+	var obj statusJSON
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &obj))
+	assert.Equal(t, "test", obj.SearchParams.CollectionName)
+	assert.Equal(t, 3, obj.Progress.Completed)
+	assert.Equal(t, 10, obj.Progress.Total)
+	assert.Equal(t, 1, obj.Progress.Errors)
+	assert.Equal(t, 1, obj.Progress.Inflight)
+}
+
+func TestStartMetricsServer(t *testing.T) {
+	s, err := startMetricsServer("", Config{}, newRecorder(0), time.Now())
+	assert.Nil(t, err)
+	assert.Nil(t, s)
+	s.Stop()
+
+	s, err = startMetricsServer("127.0.0.1:0", Config{}, newRecorder(0), time.Now())
+	assert.Nil(t, err)
+	s.Stop()
+}
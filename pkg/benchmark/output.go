@@ -0,0 +1,27 @@
+package benchmark
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	colorReset = "\033[0m"
+	colorRed   = "\033[1;31m"
+	colorWhite = "\033[0;37m"
+)
+
+// output receives the progress and informational messages of runs.
+var output io.Writer = os.Stderr
+
+// SetOutput sets where progress and informational messages are written,
+// os.Stderr by default. It must not be called while a run is in progress.
+func SetOutput(w io.Writer) {
+	output = w
+}
+
+func infof(msg string, format ...interface{}) {
+	formatted := fmt.Sprintf(msg, format...)
+	fmt.Fprintf(output, "%s%s%s\n", colorWhite, formatted, colorReset)
+}
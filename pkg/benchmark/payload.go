@@ -0,0 +1,175 @@
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/stats"
+)
+
+// nicBoundShare is the share of the NIC speed above which a run is flagged
+// as likely limited by the client NIC rather than by Milvus.
+const nicBoundShare = 0.8
+
+// payloadCounter adds up the wire size of the messages of the calls made
+// with a context carrying it, see withPayloadCounter.
+type payloadCounter struct {
+	calls    int64
+	sent     int64
+	received int64
+}
+
+type payloadCounterKey struct{}
+
+func withPayloadCounter(ctx context.Context, c *payloadCounter) context.Context {
+	return context.WithValue(ctx, payloadCounterKey{}, c)
+}
+
+// payloadHandler is the stats.Handler of every connection, it adds up the
+// sizes grpc measures anyway and so costs the calls nothing. The clients are
+// shared between runs so the counter comes with the call.
+type payloadHandler struct{}
+
+func (payloadHandler) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (payloadHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	c, ok := ctx.Value(payloadCounterKey{}).(*payloadCounter)
+	if !ok {
+		return
+	}
+	switch s := s.(type) {
+	case *stats.Begin:
+		atomic.AddInt64(&c.calls, 1)
+	case *stats.OutPayload:
+		atomic.AddInt64(&c.sent, int64(s.WireLength))
+	case *stats.InPayload:
+		atomic.AddInt64(&c.received, int64(s.WireLength))
+	}
+}
+
+func (payloadHandler) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (payloadHandler) HandleConn(context.Context, stats.ConnStats) {}
+
+// PayloadStats are the sizes of the search requests and responses of a run
+// as grpc sends and receives them, without the framing of http/2.
+type PayloadStats struct {
+	Calls                  int
+	RequestBytes           int64
+	ResponseBytes          int64
+	RequestBytesPerSecond  float64
+	ResponseBytesPerSecond float64
+	// NICSpeed is the speed of the client NIC in Mbit/s, 0 if unknown.
+	NICSpeed int
+	// NICBound is set if the payload took most of the NIC in either
+	// direction, the latencies then tell more about the client than Milvus.
+	NICBound bool
+}
+
+func (p PayloadStats) AvgRequestBytes() float64 {
+	if p.Calls == 0 {
+		return 0
+	}
+	return float64(p.RequestBytes) / float64(p.Calls)
+}
+
+func (p PayloadStats) AvgResponseBytes() float64 {
+	if p.Calls == 0 {
+		return 0
+	}
+	return float64(p.ResponseBytes) / float64(p.Calls)
+}
+
+func newPayloadStats(c *payloadCounter, took time.Duration, nicSpeed int) *PayloadStats {
+	out := &PayloadStats{
+		Calls:         int(atomic.LoadInt64(&c.calls)),
+		RequestBytes:  atomic.LoadInt64(&c.sent),
+		ResponseBytes: atomic.LoadInt64(&c.received),
+		NICSpeed:      nicSpeed,
+	}
+	out.rates(took)
+	if nicSpeed > 0 {
+		limit := nicBoundShare * float64(nicSpeed) * 1e6 / 8
+		out.NICBound = out.RequestBytesPerSecond >= limit || out.ResponseBytesPerSecond >= limit
+	}
+	return out
+}
+
+func (p *PayloadStats) rates(took time.Duration) {
+	if took <= 0 {
+		return
+	}
+	p.RequestBytesPerSecond = float64(p.RequestBytes) / took.Seconds()
+	p.ResponseBytesPerSecond = float64(p.ResponseBytes) / took.Seconds()
+}
+
+// merge adds the payload of another part of a distributed run, the parts run
+// on different machines so the NIC speed is not kept.
+func (p *PayloadStats) merge(o *PayloadStats, took time.Duration) {
+	p.Calls += o.Calls
+	p.RequestBytes += o.RequestBytes
+	p.ResponseBytes += o.ResponseBytes
+	p.NICSpeed = 0
+	p.NICBound = p.NICBound || o.NICBound
+	p.rates(took)
+}
+
+func (p PayloadStats) String() string {
+	s := fmt.Sprintf("Requests: %s total, %s avg, %s/s\nResponses: %s total, %s avg, %s/s\n",
+		formatBytes(float64(p.RequestBytes)), formatBytes(p.AvgRequestBytes()), formatBytes(p.RequestBytesPerSecond),
+		formatBytes(float64(p.ResponseBytes)), formatBytes(p.AvgResponseBytes()), formatBytes(p.ResponseBytesPerSecond))
+	if p.NICBound {
+		s += "WARNING: the client NIC is likely the bottleneck of this run\n"
+	}
+	return s
+}
+
+func formatBytes(b float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB"}
+	i := 0
+	for b >= 1024 && i < len(units)-1 {
+		b /= 1024
+		i++
+	}
+	return strconv.FormatFloat(b, 'f', 1, 64) + units[i]
+}
+
+// sysClassNet is where the speed of the network interfaces is read from.
+var sysClassNet = "/sys/class/net"
+
+// nicSpeed returns the speed in Mbit/s of the fastest interface that is up,
+// 0 if it is not known such as outside of linux or in most VMs.
+func nicSpeed() int {
+	dirs, err := filepath.Glob(filepath.Join(sysClassNet, "*"))
+	if err != nil {
+		return 0
+	}
+	var fastest int
+	for _, dir := range dirs {
+		if filepath.Base(dir) == "lo" {
+			continue
+		}
+		state, err := ioutil.ReadFile(filepath.Join(dir, "operstate"))
+		if err != nil || strings.TrimSpace(string(state)) != "up" {
+			continue
+		}
+		raw, err := ioutil.ReadFile(filepath.Join(dir, "speed"))
+		if err != nil {
+			continue
+		}
+		if speed, err := strconv.Atoi(strings.TrimSpace(string(raw))); err == nil && speed > fastest {
+			fastest = speed
+		}
+	}
+	return fastest
+}
@@ -0,0 +1,68 @@
+package benchmark
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun_payload(t *testing.T) {
+	milvus := newFakeMilvus(t, nil)
+	r, err := Run(context.Background(), testConfig(milvus.addr), Queries{{1, 2}})
+	assert.Nil(t, err)
+	p := r.Payload
+	assert.Equal(t, 10, p.Calls)
+	assert.True(t, p.RequestBytes > 0)
+	// the searches only, the calls describing the server are not counted
+	assert.Equal(t, int64(10*len(noopResponse)), p.ResponseBytes)
+	assert.Equal(t, float64(len(noopResponse)), p.AvgResponseBytes())
+	assert.True(t, p.RequestBytesPerSecond > 0)
+	assert.Equal(t, p.RequestBytes, r.toJSON().Payload.RequestBytes)
+}
+
+func TestNewPayloadStats(t *testing.T) {
+	c := &payloadCounter{calls: 100, sent: 1e6, received: 100e6}
+	p := newPayloadStats(c, time.Second, 1000)
+	assert.Equal(t, 10000.0, p.AvgRequestBytes())
+	assert.Equal(t, 100e6, p.ResponseBytesPerSecond)
+	assert.True(t, p.NICBound)
+	assert.Contains(t, p.String(), "bottleneck")
+
+	p = newPayloadStats(c, time.Second, 10000)
+	assert.False(t, p.NICBound)
+	p = newPayloadStats(c, time.Second, 0)
+	assert.False(t, p.NICBound)
+
+	merged := &PayloadStats{}
+	merged.merge(newPayloadStats(c, time.Second, 1000), 2*time.Second)
+	merged.merge(newPayloadStats(c, time.Second, 10000), 2*time.Second)
+	assert.Equal(t, 200, merged.Calls)
+	assert.Equal(t, 100e6, merged.ResponseBytesPerSecond)
+	assert.True(t, merged.NICBound)
+	assert.Equal(t, 0, merged.NICSpeed)
+}
+
+func TestNICSpeed(t *testing.T) {
+	defer func(dir string) { sysClassNet = dir }(sysClassNet)
+	sysClassNet = t.TempDir()
+	for name, files := range map[string][2]string{
+		"lo":   {"unknown", ""},
+		"eth0": {"up", "10000\n"},
+		"eth1": {"up", "25000\n"},
+		"eth2": {"down", "100000\n"},
+		"tun0": {"up", "-1\n"},
+	} {
+		dir := filepath.Join(sysClassNet, name)
+		assert.Nil(t, os.Mkdir(dir, 0755))
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "operstate"), []byte(files[0]+"\n"), 0644))
+		if files[1] != "" {
+			assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "speed"), []byte(files[1]), 0644))
+		}
+	}
+	assert.Equal(t, 25000, nicSpeed())
+}
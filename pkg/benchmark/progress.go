@@ -0,0 +1,153 @@
+package benchmark
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// progressReporter periodically prints the progress of a run. On a terminal
+// the line is rewritten in place, otherwise one plain line is written per
+// tick so that logs stay readable.
+type progressReporter struct {
+	rec      *recorder
+	total    int
+	interval time.Duration
+	out      io.Writer
+	tty      bool
+
+	// notify, if set, is called with the progress at every tick
+	notify func(Progress)
+
+	start         time.Time
+	lastTick      time.Time
+	lastCompleted int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+func newProgressReporter(rec *recorder, total int, interval time.Duration, out io.Writer, tty bool) *progressReporter {
+	return &progressReporter{
+		rec:      rec,
+		total:    total,
+		interval: interval,
+		out:      out,
+		tty:      tty,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// startProgress reports to output and notify every interval until Stop is
+// called, a zero interval disables reporting.
+func startProgress(rec *recorder, total int, interval time.Duration, notify func(Progress)) *progressReporter {
+	f, ok := output.(*os.File)
+	p := newProgressReporter(rec, total, interval, output, ok && isTerminal(f))
+	p.notify = notify
+	p.start = time.Now()
+	p.lastTick = p.start
+	if interval <= 0 {
+		close(p.done)
+		return p
+	}
+	rec.keepWindow()
+	go p.run()
+	return p
+}
+
+func (p *progressReporter) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			p.report(now)
+		case <-p.stop:
+			if p.tty {
+				// keep the last progress line and move on
+				fmt.Fprintln(p.out)
+			}
+			return
+		}
+	}
+}
+
+// Stop halts reporting and waits for the reporter to exit.
+func (p *progressReporter) Stop() {
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+	close(p.stop)
+	<-p.done
+}
+
+// Progress is the state of a run at a tick of the progress reporter, the
+// throughput and latencies are those of the last interval.
+type Progress struct {
+	Elapsed          time.Duration
+	Completed        int
+	Total            int
+	Errors           int
+	QueriesPerSecond float64
+	P50              time.Duration
+	P99              time.Duration
+}
+
+func (p *progressReporter) report(now time.Time) {
+	progress := p.progress(now, p.rec.snapshot())
+	if p.notify != nil {
+		p.notify(progress)
+	}
+	line := progress.String()
+	if p.tty {
+		fmt.Fprintf(p.out, "\r\033[2K%s%s%s", colorWhite, line, colorReset)
+	} else {
+		fmt.Fprintln(p.out, line)
+	}
+}
+
+func (p *progressReporter) progress(now time.Time, s recorderSnapshot) Progress {
+	out := Progress{
+		Elapsed:   now.Sub(p.start),
+		Completed: s.Completed,
+		Total:     p.total,
+		Errors:    s.Errors,
+	}
+	if d := now.Sub(p.lastTick); d > 0 {
+		out.QueriesPerSecond = float64(s.Completed-p.lastCompleted) / d.Seconds()
+	}
+	p.lastTick, p.lastCompleted = now, s.Completed
+	if len(s.Window) > 0 {
+		out.P50 = nearestRankPercentile(s.Window, 50)
+		out.P99 = nearestRankPercentile(s.Window, 99)
+	}
+	return out
+}
+
+func (p Progress) String() string {
+	b := strings.Builder{}
+	b.WriteString(fmt.Sprintf("[%s] %d/%d", p.Elapsed.Truncate(time.Second), p.Completed, p.Total))
+	if p.Total > 0 {
+		b.WriteString(fmt.Sprintf(" (%.1f%%)", float64(p.Completed)/float64(p.Total)*100))
+	}
+	b.WriteString(fmt.Sprintf(" qps: %.1f", p.QueriesPerSecond))
+	if p.P50 > 0 || p.P99 > 0 {
+		b.WriteString(fmt.Sprintf(" p50: %s p99: %s", p.P50, p.P99))
+	}
+	b.WriteString(fmt.Sprintf(" errors: %d", p.Errors))
+	return b.String()
+}
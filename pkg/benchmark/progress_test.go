@@ -0,0 +1,56 @@
+package benchmark
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressReporter(t *testing.T) {
+	rec := newRecorder(100)
+	rec.keepWindow()
+	for i := 1; i <= 40; i++ {
+		rec.record(opSearch, time.Duration(i)*time.Millisecond, nil)
+	}
+	rec.record(opSearch, 0, errors.New("rate limited"))
+
+	b := &strings.Builder{}
+	p := newProgressReporter(rec, 100, time.Second, b, false)
+	p.start = time.Unix(0, 0)
+	p.lastTick = p.start
+
+	p.report(p.start.Add(2 * time.Second))
+	assert.Equal(t, "[2s] 41/100 (41.0%) qps: 20.5 p50: 20ms p99: 40ms errors: 1\n", b.String())
+
+	// an idle window has neither qps nor latencies
+	b.Reset()
+	p.report(p.start.Add(4 * time.Second))
+	assert.Equal(t, "[4s] 41/100 (41.0%) qps: 0.0 errors: 1\n", b.String())
+
+	b.Reset()
+	p.tty = true
+	p.report(p.start.Add(5 * time.Second))
+	assert.True(t, strings.HasPrefix(b.String(), "\r"))
+	assert.False(t, strings.HasSuffix(b.String(), "\n"))
+
+	assert.Equal(t, 40, len(rec.latencies()))
+}
+
+func TestProgressReporter_stop(t *testing.T) {
+	rec := newRecorder(1)
+	p := startProgress(rec, 1, 0, nil)
+	p.Stop()
+	p.Stop()
+	// without a reporter the window is not kept
+	rec.record(opSearch, time.Millisecond, nil)
+	assert.Empty(t, rec.snapshot().Window)
+
+	ticks := make(chan Progress, 100)
+	p = startProgress(rec, 1, time.Millisecond, func(p Progress) { ticks <- p })
+	time.Sleep(5 * time.Millisecond)
+	p.Stop()
+	assert.Equal(t, 1, (<-ticks).Total)
+}
@@ -0,0 +1,181 @@
+package benchmark
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/zilliztech/milvus_benchmark/milvus_benchmark/benchmarker/internal/hdf5"
+	"github.com/zilliztech/milvus_benchmark/milvus_benchmark/benchmarker/internal/numpy"
+	"github.com/zilliztech/milvus_benchmark/milvus_benchmark/benchmarker/internal/vecs"
+)
+
+const (
+	queryFormatJSON  = "json"
+	queryFormatHDF5  = "hdf5"
+	queryFormatNumpy = "npy"
+	queryFormatVecs  = "vecs"
+)
+
+// DefaultQueryDataset is the dataset holding the query vectors in the
+// ann-benchmarks hdf5 files.
+const DefaultQueryDataset = "/test"
+
+var (
+	hdf5Magic  = []byte("\x89HDF\r\n\x1a\n")
+	numpyMagic = []byte("\x93NUMPY")
+)
+
+// detectQueryFormat sniffs the leading bytes of fname. TEXMEX vecs files have
+// no magic and are recognized by extension, anything that is neither an
+// HDF5 nor a NumPy file is treated as JSON.
+func detectQueryFormat(fname string) (string, error) {
+	switch vecs.TypeOf(fname) {
+	case vecs.FVECS, vecs.BVECS:
+		return queryFormatVecs, nil
+	case vecs.IVECS:
+		return "", errors.Errorf("%q holds ground truth ids, not query vectors", fname)
+	}
+
+	f, err := os.Open(fname)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head := make([]byte, len(hdf5Magic))
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	head = head[:n]
+	switch {
+	case bytes.HasPrefix(head, hdf5Magic):
+		return queryFormatHDF5, nil
+	case bytes.HasPrefix(head, numpyMagic):
+		return queryFormatNumpy, nil
+	default:
+		return queryFormatJSON, nil
+	}
+}
+
+func readJSONQueries(r io.Reader) (Queries, error) {
+	var q Queries
+	if err := json.NewDecoder(r).Decode(&q); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func readJSONQueryFile(fname string) (Queries, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readJSONQueries(f)
+}
+
+func readHDF5Queries(fname, dataset string) (Queries, error) {
+	if dataset == "" {
+		dataset = DefaultQueryDataset
+	}
+	if !strings.HasPrefix(dataset, "/") {
+		dataset = "/" + dataset
+	}
+	h, err := hdf5.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer h.Close()
+
+	d, err := h.Read(dataset)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read dataset %q", dataset)
+	}
+	q, err := d.ReadFloatMatrix()
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func readNumpyQueries(fname string) (Queries, error) {
+	obj, err := numpy.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	_, dtype, err := obj.MetaInfo()
+	if err != nil {
+		return nil, err
+	}
+	switch dtype {
+	case numpy.FLOAT32:
+		q, err := obj.ReadFloat32Matrix()
+		if err != nil {
+			return nil, err
+		}
+		return q, nil
+	case numpy.FLOAT64:
+		data, err := obj.ReadFloat64Matrix()
+		if err != nil {
+			return nil, err
+		}
+		q := make(Queries, len(data))
+		for i, row := range data {
+			q[i] = make([]float32, len(row))
+			for j, v := range row {
+				q[i][j] = float32(v)
+			}
+		}
+		return q, nil
+	default:
+		return nil, errors.Errorf("unsupported numpy data type %q, must be one of [%s, %s]",
+			dtype, numpy.FLOAT32, numpy.FLOAT64)
+	}
+}
+
+// readVecsQueries reads the query range straight from the file so that only
+// the selected rows of large base sets are loaded.
+func readVecsQueries(fname string, offset, rows int) (Queries, error) {
+	obj, err := vecs.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	total, _, _, err := obj.MetaInfo()
+	if err != nil {
+		return nil, err
+	}
+	if offset >= total {
+		return nil, errors.Errorf("query offset %d out of range, only %d vectors available",
+			offset, total)
+	}
+	if rows == 0 {
+		rows = total - offset
+	}
+	return obj.ReadFloat32Range(offset, rows)
+}
+
+// sliceQueries returns rows [offset, offset+rows) of q. A rows value of 0
+// selects everything from offset to the end.
+func sliceQueries(q Queries, offset, rows int) (Queries, error) {
+	if offset == 0 && rows == 0 {
+		return q, nil
+	}
+	if offset >= len(q) {
+		return nil, errors.Errorf("query offset %d out of range, only %d vectors available",
+			offset, len(q))
+	}
+	end := len(q)
+	if rows > 0 && offset+rows < end {
+		end = offset + rows
+	}
+	return q[offset:end], nil
+}
@@ -0,0 +1,85 @@
+package benchmark
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectQueryFormat(t *testing.T) {
+	dir := t.TempDir()
+	jsonFile := filepath.Join(dir, "queries")
+	assert.Nil(t, os.WriteFile(jsonFile, []byte("[[1, 2], [3, 4]]"), 0644))
+
+	format, err := detectQueryFormat(jsonFile)
+	assert.Nil(t, err)
+	assert.Equal(t, queryFormatJSON, format)
+
+	format, err = detectQueryFormat("../../internal/numpy/test_float.npy")
+	assert.Nil(t, err)
+	assert.Equal(t, queryFormatNumpy, format)
+
+	hdf5File := filepath.Join(dir, "queries.hdf5")
+	assert.Nil(t, os.WriteFile(hdf5File, append(hdf5Magic, 0, 0), 0644))
+	format, err = detectQueryFormat(hdf5File)
+	assert.Nil(t, err)
+	assert.Equal(t, queryFormatHDF5, format)
+
+	_, err = detectQueryFormat(filepath.Join(dir, "not_exist"))
+	assert.Error(t, err)
+}
+
+func TestParseVectors_json(t *testing.T) {
+	cfg := Config{QueryFile: "[[1, 2], [3, 4], [5, 6]]"}
+	q, err := ReadQueries(cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, Queries{{1, 2}, {3, 4}, {5, 6}}, q)
+
+	cfg.QueryOffset = 1
+	cfg.QueryRows = 1
+	q, err = ReadQueries(cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, Queries{{3, 4}}, q)
+
+	cfg.QueryOffset = 3
+	_, err = ReadQueries(cfg)
+	assert.Error(t, err)
+}
+
+func TestParseVectors_numpy(t *testing.T) {
+	cfg := Config{
+		QueryFile:   "../../internal/numpy/test_float.npy",
+		QueryOffset: 10,
+		QueryRows:   5,
+	}
+	q, err := ReadQueries(cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(q))
+	assert.Equal(t, 40, len(q[0]))
+	assert.Equal(t, float32(400), q[0][0])
+
+	_, err = ReadQueries(Config{QueryFile: "../../internal/numpy/test.npy"})
+	assert.Error(t, err)
+}
+
+func TestParseVectors_vecs(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "queries.bvecs")
+	var buf []byte
+	for i := 0; i < 4; i++ {
+		buf = append(buf, 2, 0, 0, 0, byte(i), byte(i))
+	}
+	assert.Nil(t, os.WriteFile(fname, buf, 0644))
+
+	format, err := detectQueryFormat(fname)
+	assert.Nil(t, err)
+	assert.Equal(t, queryFormatVecs, format)
+
+	q, err := ReadQueries(Config{QueryFile: fname, QueryOffset: 1, QueryRows: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, Queries{{1, 1}, {2, 2}}, q)
+
+	_, err = ReadQueries(Config{QueryFile: fname, QueryOffset: 4})
+	assert.Error(t, err)
+}
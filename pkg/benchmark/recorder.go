@@ -0,0 +1,318 @@
+package benchmark
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/status"
+)
+
+const opSearch = "search"
+
+// latencyBuckets are the upper bounds of the exported latency histogram.
+var latencyBuckets = []time.Duration{
+	time.Millisecond, 2500 * time.Microsecond, 5 * time.Millisecond, 10 * time.Millisecond,
+	25 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond, 250 * time.Millisecond,
+	500 * time.Millisecond, time.Second, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second,
+}
+
+type requestKey struct {
+	Op     string
+	Status string
+}
+
+type latencyHistogram struct {
+	// Counts[i] holds the observations in (latencyBuckets[i-1], latencyBuckets[i]],
+	// the last one everything above the largest bucket.
+	Counts []uint64
+	Sum    time.Duration
+	Count  uint64
+}
+
+func (h *latencyHistogram) observe(d time.Duration) {
+	if h.Counts == nil {
+		h.Counts = make([]uint64, len(latencyBuckets)+1)
+	}
+	i := sort.Search(len(latencyBuckets), func(i int) bool { return d <= latencyBuckets[i] })
+	h.Counts[i]++
+	h.Sum += d
+	h.Count++
+}
+
+// recorder collects the outcome of every request. It is the single path
+// feeding analyze() as well as the live progress and metrics of a run.
+type recorder struct {
+	m      sync.Mutex
+	times  []time.Duration
+	errors int
+	// finished and failed hold when each successful and failed request
+	// completed, finished is aligned with times
+	finished []time.Time
+	failed   []time.Time
+	// window holds the latencies recorded since the last snapshot, it is
+	// only kept once keepWindow was called by a progress reporter
+	window      []time.Duration
+	keepsWindow bool
+
+	requests   map[requestKey]uint64
+	histograms map[string]*latencyHistogram
+	inflight   map[string]int
+	endpoints  map[string]*endpointRecord
+	// retries counts the failed attempts that were retried and attempts the
+	// attempts of every request, they are only recorded with a retry policy
+	retries  map[requestKey]uint64
+	attempts *retryRecord
+}
+
+// endpointRecord holds the outcome of the attempts sent to one endpoint.
+type endpointRecord struct {
+	times  []time.Duration
+	errors int
+}
+
+func newRecorder(total int) *recorder {
+	return &recorder{
+		times:      make([]time.Duration, 0, total),
+		finished:   make([]time.Time, 0, total),
+		requests:   map[requestKey]uint64{},
+		histograms: map[string]*latencyHistogram{},
+		inflight:   map[string]int{},
+		endpoints:  map[string]*endpointRecord{},
+		retries:    map[requestKey]uint64{},
+	}
+}
+
+// begin marks a request of op as in flight, it must be followed by record.
+func (r *recorder) begin(op string) {
+	r.m.Lock()
+	r.inflight[op]++
+	r.m.Unlock()
+}
+
+// record records a request, latency spans all its attempts.
+func (r *recorder) record(op string, latency time.Duration, err error) {
+	now := time.Now()
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.inflight[op] > 0 {
+		r.inflight[op]--
+	}
+	r.requests[requestKey{Op: op, Status: status.Code(err).String()}]++
+	if err != nil {
+		r.errors++
+		r.failed = append(r.failed, now)
+		return
+	}
+	h, ok := r.histograms[op]
+	if !ok {
+		h = &latencyHistogram{}
+		r.histograms[op] = h
+	}
+	h.observe(latency)
+	r.times = append(r.times, latency)
+	r.finished = append(r.finished, now)
+	if r.keepsWindow {
+		r.window = append(r.window, latency)
+	}
+}
+
+// keepWindow makes r keep the latencies of the window from now on, for a
+// progress reporter to take with snapshot. Without one they would pile up
+// next to times.
+func (r *recorder) keepWindow() {
+	r.m.Lock()
+	r.keepsWindow = true
+	r.m.Unlock()
+}
+
+// recordAttempt records an attempt of a request sent to the endpoint
+// origin, a retried request has one per attempt.
+func (r *recorder) recordAttempt(origin string, latency time.Duration, err error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	e := r.endpoints[origin]
+	if e == nil {
+		e = &endpointRecord{}
+		r.endpoints[origin] = e
+	}
+	if err != nil {
+		e.errors++
+		return
+	}
+	e.times = append(e.times, latency)
+}
+
+// retry records a failed attempt of op that is about to be retried.
+func (r *recorder) retry(op string, err error) {
+	r.m.Lock()
+	r.retries[requestKey{Op: op, Status: status.Code(err).String()}]++
+	r.m.Unlock()
+}
+
+// recordAttempts records how many attempts a request recorded with record
+// took, first is the latency of its first attempt and err the error of its
+// last. Each attempt is also recorded for its endpoint with recordAttempt.
+func (r *recorder) recordAttempts(first time.Duration, attempts int, err error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.attempts == nil {
+		r.attempts = &retryRecord{}
+	}
+	r.attempts.add(first, attempts, err)
+}
+
+// retryRecord returns a copy of the attempts recorded so far, nil if none
+// were.
+func (r *recorder) retryRecord() *retryRecord {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.attempts == nil {
+		return nil
+	}
+	out := &retryRecord{}
+	out.merge(r.attempts)
+	return out
+}
+
+// latencies returns the successful latencies recorded so far.
+func (r *recorder) latencies() []time.Duration {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return append([]time.Duration{}, r.times...)
+}
+
+type recorderSnapshot struct {
+	Completed int
+	Errors    int
+	// Window is sorted, it holds the latencies since the previous snapshot.
+	Window []time.Duration
+}
+
+// snapshot returns the counters and starts a new window.
+func (r *recorder) snapshot() recorderSnapshot {
+	r.m.Lock()
+	window := r.window
+	r.window = nil
+	s := recorderSnapshot{
+		Completed: len(r.times) + r.errors,
+		Errors:    r.errors,
+		Window:    window,
+	}
+	r.m.Unlock()
+
+	sort.Slice(s.Window, func(a, b int) bool {
+		return s.Window[a] < s.Window[b]
+	})
+	return s
+}
+
+type recorderMetrics struct {
+	Requests   map[requestKey]uint64
+	Retries    map[requestKey]uint64
+	Histograms map[string]latencyHistogram
+	Inflight   map[string]int
+	Completed  int
+	Errors     int
+}
+
+// metrics returns a copy of the cumulative counters, unlike snapshot it does
+// not reset the window.
+func (r *recorder) metrics() recorderMetrics {
+	r.m.Lock()
+	defer r.m.Unlock()
+	out := recorderMetrics{
+		Requests:   make(map[requestKey]uint64, len(r.requests)),
+		Retries:    make(map[requestKey]uint64, len(r.retries)),
+		Histograms: make(map[string]latencyHistogram, len(r.histograms)),
+		Inflight:   make(map[string]int, len(r.inflight)),
+		Completed:  len(r.times) + r.errors,
+		Errors:     r.errors,
+	}
+	for k, v := range r.requests {
+		out.Requests[k] = v
+	}
+	for k, v := range r.retries {
+		out.Retries[k] = v
+	}
+	for op, h := range r.histograms {
+		out.Histograms[op] = latencyHistogram{
+			Counts: append([]uint64{}, h.Counts...),
+			Sum:    h.Sum,
+			Count:  h.Count,
+		}
+	}
+	for op, v := range r.inflight {
+		out.Inflight[op] = v
+	}
+	return out
+}
+
+// IntervalStats summarizes the requests completed within one interval of a
+// run, Offset is the end of the interval relative to the start of the run.
+type IntervalStats struct {
+	Offset           time.Duration
+	Completed        int
+	Errors           int
+	QueriesPerSecond float64
+	Mean             time.Duration
+	P50              time.Duration
+	P99              time.Duration
+}
+
+// series splits the run started at start and lasting took into intervals of
+// the given length, the last one may be shorter.
+func (r *recorder) series(start time.Time, took, interval time.Duration) []IntervalStats {
+	if interval <= 0 || took <= 0 {
+		return nil
+	}
+	n := int((took + interval - 1) / interval)
+	out := make([]IntervalStats, n)
+	windows := make([][]time.Duration, n)
+	index := func(at time.Time) int {
+		i := int(at.Sub(start) / interval)
+		if i < 0 {
+			return 0
+		}
+		if i >= n {
+			return n - 1
+		}
+		return i
+	}
+
+	r.m.Lock()
+	for i, at := range r.finished {
+		w := index(at)
+		windows[w] = append(windows[w], r.times[i])
+	}
+	for _, at := range r.failed {
+		out[index(at)].Errors++
+	}
+	r.m.Unlock()
+
+	for i := range out {
+		s := &out[i]
+		s.Offset = time.Duration(i+1) * interval
+		if s.Offset > took {
+			s.Offset = took
+		}
+		width := s.Offset - time.Duration(i)*interval
+		times := windows[i]
+		s.Completed = len(times) + s.Errors
+		s.QueriesPerSecond = float64(len(times)) / width.Seconds()
+		if len(times) == 0 {
+			continue
+		}
+		var sum time.Duration
+		for _, t := range times {
+			sum += t
+		}
+		s.Mean = sum / time.Duration(len(times))
+		sort.Slice(times, func(a, b int) bool {
+			return times[a] < times[b]
+		})
+		s.P50 = nearestRankPercentile(times, 50)
+		s.P99 = nearestRankPercentile(times, 99)
+	}
+	return out
+}
@@ -0,0 +1,239 @@
+package benchmark
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+// The html report is a single file: the tables are rendered by html/template
+// and the charts are drawn as svg by a small script fed with json, so that
+// it can be opened offline and shared as is.
+
+//go:embed report.html.tmpl
+var reportTemplateText string
+
+var reportTemplate = template.Must(template.New("report").Parse(reportTemplateText))
+
+const histogramBins = 40
+
+// HistogramBin counts the latencies in (Low, High], the first bin includes
+// Low.
+type HistogramBin struct {
+	Low   time.Duration
+	High  time.Duration
+	Count int
+}
+
+// latencyDistribution buckets the sorted times into logarithmically spaced
+// bins between the smallest and the largest one.
+func latencyDistribution(sorted []time.Duration, bins int) []HistogramBin {
+	if len(sorted) == 0 {
+		return nil
+	}
+	lo, hi := sorted[0], sorted[len(sorted)-1]
+	if lo <= 0 {
+		lo = time.Microsecond
+	}
+	if hi <= lo {
+		return []HistogramBin{{Low: sorted[0], High: hi, Count: len(sorted)}}
+	}
+	out := make([]HistogramBin, bins)
+	ratio := math.Pow(float64(hi)/float64(lo), 1/float64(bins))
+	for i := range out {
+		out[i].Low = time.Duration(float64(lo) * math.Pow(ratio, float64(i)))
+		out[i].High = time.Duration(float64(lo) * math.Pow(ratio, float64(i+1)))
+	}
+	out[0].Low = sorted[0]
+	out[bins-1].High = hi
+	for _, t := range sorted {
+		i := sort.Search(bins, func(i int) bool { return t <= out[i].High })
+		if i == bins {
+			i = bins - 1
+		}
+		out[i].Count++
+	}
+	return out
+}
+
+type reportData struct {
+	Title       string
+	GeneratedAt time.Time
+	Results     Results
+	Run         *resultsJSONRun
+	Percentiles []reportPercentile
+	Charts      reportCharts
+}
+
+type reportPercentile struct {
+	Label string
+	Value time.Duration
+}
+
+// reportCharts is embedded as json, durations are in milliseconds.
+type reportCharts struct {
+	Histogram []reportBin   `json:"histogram"`
+	Series    []reportPoint `json:"series"`
+	Sweeps    []reportSweep `json:"sweeps"`
+}
+
+type reportBin struct {
+	Low   float64 `json:"low"`
+	High  float64 `json:"high"`
+	Count int     `json:"count"`
+}
+
+type reportPoint struct {
+	Offset float64 `json:"t"`
+	QPS    float64 `json:"qps"`
+	Mean   float64 `json:"mean"`
+	P50    float64 `json:"p50"`
+	P99    float64 `json:"p99"`
+	Errors int     `json:"errors"`
+}
+
+// reportSweep plots the runs of a case that only differ by one parameter.
+type reportSweep struct {
+	Param  string             `json:"param"`
+	Points []reportSweepPoint `json:"points"`
+}
+
+type reportSweepPoint struct {
+	X   float64 `json:"x"`
+	QPS float64 `json:"qps"`
+	P99 float64 `json:"p99"`
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func newReportData(r Results, history []HistoryRecord) reportData {
+	run := r.Run.toJSON()
+	data := reportData{
+		Title:       "Benchmark report",
+		GeneratedAt: time.Now(),
+		Results:     r,
+		Run:         run,
+		Charts: reportCharts{
+			Histogram: []reportBin{},
+			Series:    []reportPoint{},
+			Sweeps:    []reportSweep{},
+		},
+	}
+	if run != nil {
+		data.Title = "Benchmark report: " + run.Config.Name
+	}
+	for i, p := range r.PercentilesLabels {
+		data.Percentiles = append(data.Percentiles, reportPercentile{Label: percentileLabel(p), Value: r.Percentiles[i]})
+	}
+	for _, b := range r.Histogram {
+		data.Charts.Histogram = append(data.Charts.Histogram, reportBin{Low: millis(b.Low), High: millis(b.High), Count: b.Count})
+	}
+	for _, s := range r.Series {
+		data.Charts.Series = append(data.Charts.Series, reportPoint{
+			Offset: s.Offset.Seconds(),
+			QPS:    s.QueriesPerSecond,
+			Mean:   millis(s.Mean),
+			P50:    millis(s.P50),
+			P99:    millis(s.P99),
+			Errors: s.Errors,
+		})
+	}
+	if run != nil {
+		data.Charts.Sweeps = append(data.Charts.Sweeps, sweeps(*run, history)...)
+	}
+	return data
+}
+
+// sweepParams are the parameters a case can be swept over.
+var sweepParams = []struct {
+	Name  string
+	Value func(resultsJSONRun) int
+}{
+	{"ef", func(r resultsJSONRun) int { return r.SearchParams.Params.Ef }},
+	{"limit", func(r resultsJSONRun) int { return r.SearchParams.Limit }},
+	{"parallel", func(r resultsJSONRun) int { return r.Config.Parallel }},
+	{"nq", func(r resultsJSONRun) int { return r.Config.Nq }},
+}
+
+// sweeps returns, for every sweep parameter, the recorded runs against the
+// same collection and index as current that differ from it by that parameter
+// only. The latest run wins when several share a value, runs without a p99
+// are left out.
+func sweeps(current resultsJSONRun, history []HistoryRecord) []reportSweep {
+	var out []reportSweep
+	for p, param := range sweepParams {
+		points := map[int]reportSweepPoint{}
+		for _, rec := range history {
+			run := rec.Results.Metadata.Run
+			if run == nil ||
+				run.SearchParams.CollectionName != current.SearchParams.CollectionName ||
+				run.SearchParams.IndexType != current.SearchParams.IndexType ||
+				run.SearchParams.MetricType != current.SearchParams.MetricType {
+				continue
+			}
+			same := true
+			for o, other := range sweepParams {
+				if o != p && other.Value(*run) != other.Value(current) {
+					same = false
+					break
+				}
+			}
+			p99, ok := rec.p99()
+			if !same || !ok {
+				continue
+			}
+			x := param.Value(*run)
+			points[x] = reportSweepPoint{
+				X:   float64(x),
+				QPS: rec.Results.Throughput.QPS,
+				P99: millis(p99),
+			}
+		}
+		if len(points) < 2 {
+			continue
+		}
+		sweep := reportSweep{Param: param.Name}
+		for _, point := range points {
+			sweep.Points = append(sweep.Points, point)
+		}
+		sort.Slice(sweep.Points, func(a, b int) bool {
+			return sweep.Points[a].X < sweep.Points[b].X
+		})
+		out = append(out, sweep)
+	}
+	return out
+}
+
+// WriteReportTo writes r as a self-contained html page, the sweeps are built
+// from history which may be empty.
+func (r Results) WriteReportTo(w io.Writer, history []HistoryRecord) error {
+	return reportTemplate.Execute(w, newReportData(r, history))
+}
+
+// writeReport writes the report of r to fname, with sweep plots when the run
+// is recorded in historyFile.
+func writeReport(fname string, r Results, historyFile string) error {
+	var history []HistoryRecord
+	if historyFile != "" {
+		var err error
+		history, err = ReadHistory(historyFile)
+		if err != nil {
+			return err
+		}
+	}
+	f, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	if err := r.WriteReportTo(f, history); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
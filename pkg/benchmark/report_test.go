@@ -0,0 +1,118 @@
+package benchmark
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyDistribution(t *testing.T) {
+	r := testResults()
+	assert.Equal(t, histogramBins, len(r.Histogram))
+	count := 0
+	for i, b := range r.Histogram {
+		assert.True(t, b.Low < b.High)
+		if i > 0 {
+			assert.Equal(t, r.Histogram[i-1].High, b.Low)
+		}
+		count += b.Count
+	}
+	assert.Equal(t, 100, count)
+	assert.Equal(t, time.Millisecond, r.Histogram[0].Low)
+	assert.Equal(t, 100*time.Millisecond, r.Histogram[histogramBins-1].High)
+
+	same := latencyDistribution([]time.Duration{time.Millisecond, time.Millisecond}, histogramBins)
+	assert.Equal(t, []HistogramBin{{Low: time.Millisecond, High: time.Millisecond, Count: 2}}, same)
+	assert.Nil(t, latencyDistribution(nil, histogramBins))
+}
+
+func TestRecorderSeries(t *testing.T) {
+	rec := newRecorder(10)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		rec.record(opSearch, time.Duration(i+1)*time.Millisecond, nil)
+	}
+	rec.record(opSearch, 0, assert.AnError)
+	rec.finished[2] = start.Add(1500 * time.Millisecond)
+	rec.finished[3] = start.Add(1600 * time.Millisecond)
+
+	series := rec.series(start, 2500*time.Millisecond, time.Second)
+	assert.Equal(t, 3, len(series))
+	assert.Equal(t, IntervalStats{
+		Offset: time.Second, Completed: 3, Errors: 1, QueriesPerSecond: 2,
+		Mean: 1500 * time.Microsecond, P50: time.Millisecond, P99: 2 * time.Millisecond,
+	}, series[0])
+	assert.Equal(t, 2, series[1].Completed)
+	assert.Equal(t, 4*time.Millisecond, series[1].P99)
+	assert.Equal(t, 2500*time.Millisecond, series[2].Offset)
+	assert.Equal(t, 0.0, series[2].QueriesPerSecond)
+
+	assert.Nil(t, rec.series(start, time.Second, 0))
+}
+
+func TestSweeps(t *testing.T) {
+	var history []HistoryRecord
+	add := func(ef, parallel int, qps float64) resultsJSONRun {
+		cfg := Config{Parallel: parallel, Nq: 1}
+		cfg.CollectionName = "sift"
+		cfg.IndexType = "HNSW"
+		cfg.Limit = 10
+		cfg.Params.Ef = ef
+		r := testResults()
+		r.QueriesPerSecond = qps
+		r.Run = newRunInfo(cfg, ServerInfo{}, time.Now(), time.Now())
+		history = append(history, HistoryRecord{Results: r.toJSON()})
+		return *r.Run.toJSON()
+	}
+	add(64, 1, 100)
+	add(32, 1, 150)
+	add(64, 8, 700)
+	add(128, 8, 500)
+	add(64, 1, 110)
+	add(256, 1, 60)
+	delete(history[len(history)-1].Results.Latencies, "p99")
+	other := add(16, 4, 1000)
+	current := add(128, 1, 80)
+
+	out := sweeps(current, history)
+	assert.Equal(t, 2, len(out))
+	assert.Equal(t, "ef", out[0].Param)
+	assert.Equal(t, []reportSweepPoint{
+		{X: 32, QPS: 150, P99: 99},
+		{X: 64, QPS: 110, P99: 99},
+		{X: 128, QPS: 80, P99: 99},
+	}, out[0].Points)
+	assert.Equal(t, "parallel", out[1].Param)
+	assert.Equal(t, 2, len(out[1].Points))
+
+	assert.Equal(t, 0, len(sweeps(other, history)))
+}
+
+func TestWriteReportTo(t *testing.T) {
+	cfg := Config{Name: "hnsw_top10", Total: 102, Parallel: 4}
+	cfg.CollectionName = "sift"
+	r := testResults()
+	r.Run = newRunInfo(cfg, ServerInfo{Version: "v2.1.0"}, time.Now(), time.Now())
+	r.Series = []IntervalStats{{Offset: time.Second, Completed: 102, QueriesPerSecond: 100, P99: 99 * time.Millisecond}}
+	r.Assertions = []AssertionResult{{Assertion: "p99<50ms", Actual: "99ms"}}
+
+	b := &strings.Builder{}
+	assert.Nil(t, r.WriteReportTo(b, nil))
+	out := b.String()
+	assert.Contains(t, out, "<title>Benchmark report: hnsw_top10</title>")
+	assert.Contains(t, out, "v2.1.0")
+	assert.Contains(t, out, "<td>p99</td><td class=\"num\">99ms</td>")
+	assert.Contains(t, out, `<td class="fail">FAIL</td><td>p99&lt;50ms</td>`)
+	assert.Contains(t, out, `"qps":100`)
+	assert.Contains(t, out, `"sweeps":[]`)
+	// nothing is loaded from the network
+	assert.False(t, regexp.MustCompile(`(src|href)\s*=\s*["']?(https?:)?//`).MatchString(out))
+	assert.NotContains(t, out, "@import")
+
+	b.Reset()
+	assert.Nil(t, Results{}.WriteReportTo(b, nil))
+	assert.Contains(t, b.String(), "<title>Benchmark report</title>")
+}
@@ -0,0 +1,239 @@
+package benchmark
+
+import (
+	"fmt"
+	"strings"
+
+	milvusClient "github.com/xiaocai2333/milvus-sdk-go/v2/client"
+	"github.com/xiaocai2333/milvus-sdk-go/v2/entity"
+)
+
+// Anomalies Config.CheckResults looks for in the hits of every query.
+const (
+	AnomalyEmpty         = "empty"
+	AnomalyShort         = "short"
+	AnomalyUnsorted      = "unsorted"
+	AnomalyDuplicateIDs  = "duplicate_ids"
+	AnomalyMissingFields = "missing_fields"
+)
+
+var anomalyKinds = []string{AnomalyEmpty, AnomalyShort, AnomalyUnsorted, AnomalyDuplicateIDs, AnomalyMissingFields}
+
+// maxAnomalyExamples is the number of examples kept per anomaly.
+const maxAnomalyExamples = 3
+
+// ResultCheck is what the check of the search results found, the queries
+// are counted one by one rather than per request.
+type ResultCheck struct {
+	Checked int
+	// Invalid is the number of queries with at least one anomaly.
+	Invalid   int
+	Anomalies map[string]AnomalyStats
+}
+
+type AnomalyStats struct {
+	Count    int
+	Examples []AnomalyExample
+}
+
+// AnomalyExample points at a query whose hits were found wrong, Request is
+// the index of the request in the run and Query that of the vector in it.
+type AnomalyExample struct {
+	Request int
+	Query   int
+	Detail  string
+	Vector  []float32
+}
+
+func (c *ResultCheck) add(kind string, example AnomalyExample) {
+	if c.Anomalies == nil {
+		c.Anomalies = map[string]AnomalyStats{}
+	}
+	s := c.Anomalies[kind]
+	s.Count++
+	if len(s.Examples) < maxAnomalyExamples {
+		s.Examples = append(s.Examples, example)
+	}
+	c.Anomalies[kind] = s
+}
+
+// merge adds the findings of another part of a distributed run.
+func (c *ResultCheck) merge(o *ResultCheck) {
+	c.Checked += o.Checked
+	c.Invalid += o.Invalid
+	for _, kind := range anomalyKinds {
+		s, ok := o.Anomalies[kind]
+		if !ok {
+			continue
+		}
+		if c.Anomalies == nil {
+			c.Anomalies = map[string]AnomalyStats{}
+		}
+		mine := c.Anomalies[kind]
+		mine.Count += s.Count
+		for _, e := range s.Examples {
+			if len(mine.Examples) < maxAnomalyExamples {
+				mine.Examples = append(mine.Examples, e)
+			}
+		}
+		c.Anomalies[kind] = mine
+	}
+}
+
+// checkedSearch is a successful search queued for the check.
+type checkedSearch struct {
+	request int
+	queries []entity.Vector
+	results []milvusClient.SearchResult
+}
+
+// resultChecker checks the hits of the searches of a run from its own
+// goroutine, the same as traceWriter. The workers only wait for the check
+// once traceBuffer searches are queued.
+type resultChecker struct {
+	limit        int
+	descending   bool
+	outputFields []string
+
+	searches chan checkedSearch
+	done     chan struct{}
+	out      ResultCheck
+}
+
+func newResultChecker(cfg Config) *resultChecker {
+	if !cfg.CheckResults {
+		return nil
+	}
+	return &resultChecker{
+		limit: cfg.Limit,
+		// inner product is a similarity, the other metrics are distances
+		descending:   entity.MetricType(cfg.MetricType) == entity.IP,
+		outputFields: cfg.OutputFields,
+	}
+}
+
+// startResultCheck starts checking the searches of a run, it returns nil if
+// cfg does not check the results.
+func startResultCheck(cfg Config) *resultChecker {
+	c := newResultChecker(cfg)
+	if c == nil {
+		return nil
+	}
+	c.searches = make(chan checkedSearch, traceBuffer)
+	c.done = make(chan struct{})
+	go func() {
+		for s := range c.searches {
+			c.check(s)
+		}
+		close(c.done)
+	}()
+	return c
+}
+
+// add queues a search, it is a no-op on a nil resultChecker.
+func (c *resultChecker) add(request int, queries []entity.Vector, results []milvusClient.SearchResult) {
+	if c != nil {
+		c.searches <- checkedSearch{request: request, queries: queries, results: results}
+	}
+}
+
+// Close checks the queued searches and returns what the check found, it must
+// be called once the workers are done. It returns nil on a nil resultChecker.
+func (c *resultChecker) Close() *ResultCheck {
+	if c == nil {
+		return nil
+	}
+	close(c.searches)
+	<-c.done
+	out := c.out
+	return &out
+}
+
+func (c *resultChecker) check(s checkedSearch) {
+	for i, query := range s.queries {
+		var hits milvusClient.SearchResult
+		if i < len(s.results) {
+			hits = s.results[i]
+		}
+		found := c.anomalies(hits)
+
+		c.out.Checked++
+		if len(found) > 0 {
+			c.out.Invalid++
+		}
+		for _, kind := range anomalyKinds {
+			detail, ok := found[kind]
+			if !ok {
+				continue
+			}
+			example := AnomalyExample{Request: s.request, Query: i, Detail: detail}
+			if v, ok := query.(entity.FloatVector); ok {
+				example.Vector = append([]float32{}, v...)
+			}
+			c.out.add(kind, example)
+		}
+	}
+}
+
+// anomalies returns the anomalies of the hits of one query with a short
+// description of each.
+func (c *resultChecker) anomalies(hits milvusClient.SearchResult) map[string]string {
+	found := map[string]string{}
+	if hits.ResultCount == 0 {
+		found[AnomalyEmpty] = "no hits"
+		return found
+	}
+	if hits.ResultCount < c.limit {
+		found[AnomalyShort] = fmt.Sprintf("%d of %d hits", hits.ResultCount, c.limit)
+	}
+	for j := 1; j < len(hits.Scores); j++ {
+		prev, cur := hits.Scores[j-1], hits.Scores[j]
+		if (c.descending && cur > prev) || (!c.descending && cur < prev) {
+			found[AnomalyUnsorted] = fmt.Sprintf("score %g at rank %d follows %g", cur, j, prev)
+			break
+		}
+	}
+	if id, ok := duplicateID(hits.IDs); ok {
+		found[AnomalyDuplicateIDs] = fmt.Sprintf("id %s returned more than once", id)
+	}
+	var missing []string
+	for _, name := range c.outputFields {
+		present := false
+		for _, f := range hits.Fields {
+			if f != nil && f.Name() == name {
+				present = true
+				break
+			}
+		}
+		if !present {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		found[AnomalyMissingFields] = "missing " + strings.Join(missing, ", ")
+	}
+	return found
+}
+
+// duplicateID returns the first id found twice in ids.
+func duplicateID(ids entity.Column) (string, bool) {
+	switch ids := ids.(type) {
+	case *entity.ColumnInt64:
+		seen := make(map[int64]bool, ids.Len())
+		for _, id := range ids.Data() {
+			if seen[id] {
+				return fmt.Sprint(id), true
+			}
+			seen[id] = true
+		}
+	case *entity.ColumnString:
+		seen := make(map[string]bool, ids.Len())
+		for _, id := range ids.Data() {
+			if seen[id] {
+				return id, true
+			}
+			seen[id] = true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,132 @@
+package benchmark
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	milvusClient "github.com/xiaocai2333/milvus-sdk-go/v2/client"
+	"github.com/xiaocai2333/milvus-sdk-go/v2/entity"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestResultChecker_anomalies(t *testing.T) {
+	cfg := Config{CheckResults: true}
+	cfg.Limit = 3
+	cfg.MetricType = "L2"
+	cfg.OutputFields = []string{"title"}
+	c := newResultChecker(cfg)
+	title := entity.NewColumnString("title", []string{"a", "b", "c"})
+
+	assert.Empty(t, c.anomalies(milvusClient.SearchResult{
+		ResultCount: 3,
+		IDs:         entity.NewColumnInt64("", []int64{1, 2, 3}),
+		Scores:      []float32{0.1, 0.2, 0.2},
+		Fields:      []entity.Column{title},
+	}))
+	assert.Equal(t, map[string]string{AnomalyEmpty: "no hits"}, c.anomalies(milvusClient.SearchResult{}))
+	assert.Equal(t, map[string]string{
+		AnomalyShort:         "2 of 3 hits",
+		AnomalyUnsorted:      "score 0.1 at rank 1 follows 0.2",
+		AnomalyDuplicateIDs:  "id 7 returned more than once",
+		AnomalyMissingFields: "missing title",
+	}, c.anomalies(milvusClient.SearchResult{
+		ResultCount: 2,
+		IDs:         entity.NewColumnInt64("", []int64{7, 7}),
+		Scores:      []float32{0.2, 0.1},
+	}))
+
+	cfg.MetricType = "IP"
+	c = newResultChecker(cfg)
+	assert.Empty(t, c.anomalies(milvusClient.SearchResult{
+		ResultCount: 3,
+		IDs:         entity.NewColumnString("", []string{"x", "y", "z"}),
+		Scores:      []float32{0.9, 0.5, 0.1},
+		Fields:      []entity.Column{title},
+	}))
+
+	assert.Nil(t, newResultChecker(Config{}))
+}
+
+func TestResultCheck_merge(t *testing.T) {
+	var a, b ResultCheck
+	for i := 0; i < 2; i++ {
+		a.add(AnomalyEmpty, AnomalyExample{Request: i})
+		b.add(AnomalyEmpty, AnomalyExample{Request: 10 + i})
+	}
+	b.add(AnomalyShort, AnomalyExample{Request: 12})
+	a.Checked, a.Invalid, b.Checked, b.Invalid = 5, 2, 5, 3
+	a.merge(&b)
+	assert.Equal(t, 10, a.Checked)
+	assert.Equal(t, 5, a.Invalid)
+	assert.Equal(t, 4, a.Anomalies[AnomalyEmpty].Count)
+	assert.Equal(t, maxAnomalyExamples, len(a.Anomalies[AnomalyEmpty].Examples))
+	assert.Equal(t, 1, a.Anomalies[AnomalyShort].Count)
+}
+
+// searchResponse encodes the SearchResults of a single query with int64 ids.
+func searchResponse(ids []int64, scores []float32) []byte {
+	var longs []byte
+	for _, id := range ids {
+		longs = protowire.AppendVarint(longs, uint64(id))
+	}
+	var idArray []byte
+	idArray = protowire.AppendTag(idArray, 1, protowire.BytesType)
+	idArray = protowire.AppendBytes(idArray, longs)
+	var idField []byte
+	idField = protowire.AppendTag(idField, 1, protowire.BytesType)
+	idField = protowire.AppendBytes(idField, idArray)
+
+	var packedScores []byte
+	for _, s := range scores {
+		packedScores = protowire.AppendFixed32(packedScores, math.Float32bits(s))
+	}
+
+	var data []byte
+	data = protowire.AppendTag(data, 1, protowire.VarintType)
+	data = protowire.AppendVarint(data, 1)
+	data = protowire.AppendTag(data, 2, protowire.VarintType)
+	data = protowire.AppendVarint(data, uint64(len(ids)))
+	data = protowire.AppendTag(data, 4, protowire.BytesType)
+	data = protowire.AppendBytes(data, packedScores)
+	data = protowire.AppendTag(data, 5, protowire.BytesType)
+	data = protowire.AppendBytes(data, idField)
+	data = protowire.AppendTag(data, 6, protowire.BytesType)
+	data = protowire.AppendBytes(data, protowire.AppendVarint(nil, uint64(len(ids))))
+
+	resp := append([]byte{}, noopResponse...)
+	resp = protowire.AppendTag(resp, 2, protowire.BytesType)
+	return protowire.AppendBytes(resp, data)
+}
+
+func TestRun_checkResults(t *testing.T) {
+	milvus := newFakeMilvus(t, func(method string, _ []byte) ([]byte, error) {
+		if method == searchMethod {
+			return searchResponse([]int64{4, 4}, []float32{0.5, 0.1}), nil
+		}
+		return nil, nil
+	})
+	cfg := testConfig(milvus.addr)
+	cfg.MetricType = "L2"
+	cfg.CheckResults = true
+	cfg.Assert = []string{"invalid_results<1"}
+	r, err := Run(context.Background(), cfg, Queries{{1, 2}})
+	assert.Equal(t, ErrAssertionsFailed, err)
+	assert.Equal(t, 10, r.Successful)
+	assert.Equal(t, 10, r.ResultCheck.Checked)
+	assert.Equal(t, 10, r.ResultCheck.Invalid)
+	for _, kind := range []string{AnomalyShort, AnomalyUnsorted, AnomalyDuplicateIDs} {
+		assert.Equal(t, 10, r.ResultCheck.Anomalies[kind].Count, kind)
+	}
+	examples := r.ResultCheck.Anomalies[AnomalyShort].Examples
+	assert.Equal(t, maxAnomalyExamples, len(examples))
+	assert.Equal(t, "2 of 10 hits", examples[0].Detail)
+	assert.Equal(t, []float32{1, 2}, examples[0].Vector)
+	assert.Equal(t, 10, r.toJSON().ResultCheck.Anomalies[AnomalyUnsorted].Count)
+
+	cfg.CheckResults = false
+	r, err = Run(context.Background(), cfg, Queries{{1, 2}})
+	assert.Equal(t, ErrAssertionsFailed, err)
+	assert.Equal(t, "results are not checked in this run", r.Assertions[0].Actual)
+}
@@ -0,0 +1,105 @@
+package benchmark
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// summaryHeader returns the column names shared by the csv and markdown
+// writers, one row of summaryRow per case lines up with them. unit is
+// appended to the names of the latency columns.
+func (r Results) summaryHeader(unit string) []string {
+	header := []string{"case", "total", "successful", "failed", "parallelization", "took" + unit, "qps"}
+	latencies := []string{"min", "mean", "max", "stddev"}
+	for _, percentile := range r.PercentilesLabels {
+		latencies = append(latencies, percentileLabel(percentile))
+	}
+	for _, l := range latencies {
+		header = append(header, l+unit)
+	}
+	return header
+}
+
+func (r Results) summaryRow(formatDuration func(time.Duration) string) []string {
+	row := []string{
+		r.Run.Config.CaseName(),
+		fmt.Sprint(r.Total),
+		fmt.Sprint(r.Successful),
+		fmt.Sprint(r.Failed),
+		fmt.Sprint(r.Parallelization),
+		formatDuration(r.Took),
+		fmt.Sprintf("%f", r.QueriesPerSecond),
+		formatDuration(r.Min),
+		formatDuration(r.Mean),
+		formatDuration(r.Max),
+		formatDuration(r.StdDev),
+	}
+	for _, p := range r.Percentiles {
+		row = append(row, formatDuration(p))
+	}
+	return row
+}
+
+func formatMillis(d time.Duration) string {
+	return fmt.Sprintf("%.3f", float64(d)/float64(time.Millisecond))
+}
+
+// WriteCsvTo writes a header and a single row, durations are in
+// milliseconds. The first column is the case name, so that the rows of
+// several runs written with WriteCsvRowTo can share one sheet.
+func (r Results) WriteCsvTo(w io.Writer) (int, error) {
+	return r.writeCsv(w, true)
+}
+
+// WriteCsvRowTo writes the row of WriteCsvTo without its header, to append
+// a run to a sheet of runs with the same percentiles.
+func (r Results) WriteCsvRowTo(w io.Writer) (int, error) {
+	return r.writeCsv(w, false)
+}
+
+func (r Results) writeCsv(w io.Writer, header bool) (int, error) {
+	b := &strings.Builder{}
+	cw := csv.NewWriter(b)
+	if header {
+		if err := cw.Write(r.summaryHeader("_ms")); err != nil {
+			return 0, err
+		}
+	}
+	if err := cw.Write(r.summaryRow(formatMillis)); err != nil {
+		return 0, err
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return 0, err
+	}
+	return w.Write([]byte(b.String()))
+}
+
+func (r Results) WriteMarkdownTo(w io.Writer) (int, error) {
+	header := r.summaryHeader("")
+	sep := make([]string, len(header))
+	for i := range sep {
+		sep[i] = "---:"
+	}
+	// the case name is text
+	sep[0] = "---"
+
+	b := strings.Builder{}
+	for _, row := range [][]string{header, sep, r.summaryRow(func(d time.Duration) string { return fmt.Sprint(d) })} {
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+	}
+	if len(r.Assertions) > 0 {
+		b.WriteString("\n| assertion | actual | result |\n| --- | ---: | --- |\n")
+	}
+	for _, a := range r.Assertions {
+		result := "pass"
+		if !a.Passed {
+			result = "**FAIL**"
+		}
+		b.WriteString(fmt.Sprintf("| `%s` | %s | %s |\n", a.Assertion, a.Actual, result))
+	}
+	return w.Write([]byte(b.String()))
+}
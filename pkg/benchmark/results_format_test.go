@@ -0,0 +1,86 @@
+package benchmark
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testResults() Results {
+	times := make([]time.Duration, 0, 100)
+	for i := 1; i <= 100; i++ {
+		times = append(times, time.Duration(i)*time.Millisecond)
+	}
+	return analyze(Config{Total: 102, Parallel: 4}, times, time.Second)
+}
+
+func TestWriteCsvTo(t *testing.T) {
+	r := testResults()
+	r.Run.Config.Name = "sweep_ef64"
+	b := &strings.Builder{}
+	_, err := r.WriteCsvTo(b)
+	assert.Nil(t, err)
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	assert.Equal(t, 2, len(lines))
+	assert.Equal(t, "case,total,successful,failed,parallelization,took_ms,qps,min_ms,mean_ms,max_ms,stddev_ms,p50_ms,p90_ms,p95_ms,p98_ms,p99_ms", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "sweep_ef64,102,100,2,4,1000.000,100.000000,1.000,50.500,100.000,"))
+
+	r.Run.Config.Name = "sweep_ef128"
+	_, err = r.WriteCsvRowTo(b)
+	assert.Nil(t, err)
+	lines = strings.Split(strings.TrimSpace(b.String()), "\n")
+	assert.Equal(t, 3, len(lines))
+	assert.True(t, strings.HasPrefix(lines[2], "sweep_ef128,102,"))
+}
+
+func TestWriteMarkdownTo(t *testing.T) {
+	b := &strings.Builder{}
+	_, err := testResults().WriteMarkdownTo(b)
+	assert.Nil(t, err)
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	assert.Equal(t, 3, len(lines))
+	assert.True(t, strings.HasPrefix(lines[0], "| case | total | successful | failed |"))
+	assert.True(t, strings.HasPrefix(lines[1], "| --- | ---: |"))
+	assert.Contains(t, lines[2], "| 100ms |")
+}
+
+func TestWriteTextTo(t *testing.T) {
+	b := &strings.Builder{}
+	_, err := testResults().WriteTextTo(b)
+	assert.Nil(t, err)
+
+	assert.Contains(t, b.String(), "Failed: 2\n")
+	assert.Contains(t, b.String(), "Max: 100ms\n")
+	assert.Contains(t, b.String(), "StdDev: ")
+	assert.Contains(t, b.String(), "p99: 99ms\n")
+	assert.NotContains(t, b.String(), "\"")
+}
+
+func TestAnalyze_percentiles(t *testing.T) {
+	times := []time.Duration{4, 1, 3, 2, 5, 10, 6, 7, 9, 8}
+	cfg := Config{Total: 10, Percentiles: []float64{10, 50, 90, 99.9, 100}}
+
+	r := analyze(cfg, append([]time.Duration{}, times...), time.Second)
+	assert.Equal(t, []time.Duration{1, 5, 9, 10, 10}, r.Percentiles)
+	assert.Equal(t, time.Duration(10), r.Max)
+	assert.Equal(t, time.Duration(2), r.StdDev)
+
+	cfg.PercentileMethod = PercentileLinear
+	r = analyze(cfg, append([]time.Duration{}, times...), time.Second)
+	assert.Equal(t, []time.Duration{1, 5, 9, 9, 10}, r.Percentiles)
+
+	b := &strings.Builder{}
+	_, err := r.WriteJsonTo(b)
+	assert.Nil(t, err)
+	assert.Contains(t, b.String(), `"p99.9": 9`)
+	assert.Contains(t, b.String(), `"max": 10`)
+	assert.Contains(t, b.String(), `"stddev": 2`)
+
+	r = analyze(Config{Total: 1}, nil, time.Second)
+	assert.Equal(t, 1, r.Failed)
+	assert.Equal(t, time.Duration(0), r.Mean)
+}
@@ -0,0 +1,226 @@
+package benchmark
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultRetryCodes are the grpc status codes retried unless
+// RetryPolicy.Codes is set, those of rate limiting and of a proxy that is
+// restarting.
+var DefaultRetryCodes = []string{codes.Unavailable.String(), codes.ResourceExhausted.String()}
+
+// RetryPolicy says which failed searches are sent again. A request failing
+// with a retryable code is retried until MaxAttempts attempts failed, it then
+// counts as failed without stopping the run. Any other error still stops the
+// run.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts of a request including the
+	// first, requests are not retried if it is 0 or 1.
+	MaxAttempts int
+	// Backoff is the wait before the first retry, doubled for every retry
+	// up to MaxBackoff if it is set. The wait actually is a random duration
+	// between half and all of it.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Codes are the names of the retryable grpc status codes such as
+	// Unavailable, DefaultRetryCodes if empty.
+	Codes []string
+}
+
+func (p RetryPolicy) enabled() bool {
+	return p.MaxAttempts > 1
+}
+
+func (p RetryPolicy) validate() error {
+	if p.MaxAttempts < 0 {
+		return errors.Errorf("retryMaxAttempts must not be negative")
+	}
+	if p.Backoff < 0 || p.MaxBackoff < 0 {
+		return errors.Errorf("retryBackoff and retryMaxBackoff must not be negative")
+	}
+	for _, c := range p.Codes {
+		if _, err := parseStatus(c); err != nil {
+			return errors.Errorf("unsupported retry code %q, must be a grpc status code such as %s",
+				c, strings.Join(DefaultRetryCodes, " or "))
+		}
+	}
+	return nil
+}
+
+// retryable tells whether a request failing with err may be sent again.
+func (p RetryPolicy) retryable(err error) bool {
+	if err == nil || !p.enabled() {
+		return false
+	}
+	retryCodes := p.Codes
+	if len(retryCodes) == 0 {
+		retryCodes = DefaultRetryCodes
+	}
+	code := status.Code(err).String()
+	for _, c := range retryCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the wait after the given failed attempt, drawn from rng so
+// that it follows the seed of the run.
+func (p RetryPolicy) backoff(attempt int, rng *rand.Rand) time.Duration {
+	limit := p.MaxBackoff
+	if limit == 0 {
+		limit = math.MaxInt64 / 2
+	}
+	d := p.Backoff
+	for i := 1; i < attempt && d < limit; i++ {
+		d *= 2
+	}
+	if d > limit {
+		d = limit
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rng.Int63n(int64(d/2)+1))
+}
+
+// retryRecord holds the attempts of the requests of a run with a retry
+// policy. Like AgentResult.Latencies it keeps the latencies of the first
+// attempts at microsecond resolution, so that it is cheap to send.
+type retryRecord struct {
+	FirstAttempts map[int64]uint64
+	// Attempts[i] is the number of requests sent i+1 times.
+	Attempts []int
+	// Recovered requests failed at first and succeeded on a retry.
+	Recovered int
+	Failed    int
+}
+
+func (r *retryRecord) add(first time.Duration, attempts int, err error) {
+	if r.FirstAttempts == nil {
+		r.FirstAttempts = map[int64]uint64{}
+	}
+	r.FirstAttempts[first.Microseconds()]++
+	for len(r.Attempts) < attempts {
+		r.Attempts = append(r.Attempts, 0)
+	}
+	r.Attempts[attempts-1]++
+	switch {
+	case err != nil:
+		r.Failed++
+	case attempts > 1:
+		r.Recovered++
+	}
+}
+
+func (r *retryRecord) merge(other *retryRecord) {
+	if r.FirstAttempts == nil {
+		r.FirstAttempts = map[int64]uint64{}
+	}
+	for us, count := range other.FirstAttempts {
+		r.FirstAttempts[us] += count
+	}
+	for len(r.Attempts) < len(other.Attempts) {
+		r.Attempts = append(r.Attempts, 0)
+	}
+	for i, n := range other.Attempts {
+		r.Attempts[i] += n
+	}
+	r.Recovered += other.Recovered
+	r.Failed += other.Failed
+}
+
+// RetryStats tells how much retrying a run took. The latencies of Results
+// span all the attempts of a request, the first attempt ones those of the
+// first attempt of every request whether it failed or not.
+type RetryStats struct {
+	MaxAttempts int
+	// Attempts[i] is the number of requests sent i+1 times.
+	Attempts []int
+	// Retried is the number of requests sent more than once and Retries the
+	// number of attempts after the first ones.
+	Retried int
+	Retries int
+	// Recovered requests succeeded on a retry, FinalFailures still failed
+	// on their last attempt.
+	Recovered               int
+	FinalFailures           int
+	FirstAttemptMean        time.Duration
+	FirstAttemptMax         time.Duration
+	FirstAttemptPercentiles []time.Duration
+}
+
+// stats returns the RetryStats of the run described by cfg, percentiles are
+// those of its Results. It returns nil for runs without a retry policy.
+func (r *retryRecord) stats(cfg Config, percentiles []float64) *RetryStats {
+	if r == nil {
+		return nil
+	}
+	out := &RetryStats{
+		MaxAttempts:   cfg.Retry.MaxAttempts,
+		Attempts:      r.Attempts,
+		Recovered:     r.Recovered,
+		FinalFailures: r.Failed,
+	}
+	for i, n := range r.Attempts {
+		if i > 0 {
+			out.Retried += n
+			out.Retries += i * n
+		}
+	}
+	first := expandLatencies(r.FirstAttempts)
+	out.FirstAttemptPercentiles = make([]time.Duration, len(percentiles))
+	if len(first) == 0 {
+		return out
+	}
+	var sum time.Duration
+	for _, t := range first {
+		sum += t
+	}
+	out.FirstAttemptMean = sum / time.Duration(len(first))
+	sort.Slice(first, func(a, b int) bool {
+		return first[a] < first[b]
+	})
+	out.FirstAttemptMax = first[len(first)-1]
+	for i, percentile := range percentiles {
+		if cfg.PercentileMethod == PercentileLinear {
+			out.FirstAttemptPercentiles[i] = linearPercentile(first, percentile)
+		} else {
+			out.FirstAttemptPercentiles[i] = nearestRankPercentile(first, percentile)
+		}
+	}
+	return out
+}
+
+// expandLatencies turns latencies counted by microsecond back into a slice.
+func expandLatencies(counts map[int64]uint64) []time.Duration {
+	var out []time.Duration
+	for us, count := range counts {
+		for i := uint64(0); i < count; i++ {
+			out = append(out, time.Duration(us)*time.Microsecond)
+		}
+	}
+	return out
+}
+
+func (s *RetryStats) String(labels []float64) string {
+	b := strings.Builder{}
+	b.WriteString(fmt.Sprintf("Max attempts: %d\nRetried: %d\nRetries: %d\nRecovered: %d\nFinal failures: %d\n",
+		s.MaxAttempts, s.Retried, s.Retries, s.Recovered, s.FinalFailures))
+	b.WriteString(fmt.Sprintf("First attempt: mean %s, max %s", s.FirstAttemptMean, s.FirstAttemptMax))
+	for i, percentile := range labels {
+		b.WriteString(fmt.Sprintf(", %s %s", percentileLabel(percentile), s.FirstAttemptPercentiles[i]))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
@@ -0,0 +1,156 @@
+package benchmark
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 3}
+	assert.True(t, p.retryable(status.Error(codes.Unavailable, "")))
+	assert.True(t, p.retryable(status.Error(codes.ResourceExhausted, "")))
+	assert.False(t, p.retryable(status.Error(codes.InvalidArgument, "")))
+	assert.False(t, p.retryable(errors.New("not a grpc error")))
+	assert.False(t, p.retryable(nil))
+	assert.False(t, RetryPolicy{MaxAttempts: 1}.retryable(status.Error(codes.Unavailable, "")))
+	p.Codes = []string{"DeadlineExceeded"}
+	assert.True(t, p.retryable(status.Error(codes.DeadlineExceeded, "")))
+	assert.False(t, p.retryable(status.Error(codes.Unavailable, "")))
+
+	assert.Nil(t, p.validate())
+	assert.Error(t, RetryPolicy{Codes: []string{"unavailable"}}.validate())
+	assert.Error(t, RetryPolicy{MaxAttempts: -1}.validate())
+	assert.Error(t, RetryPolicy{Backoff: -time.Second}.validate())
+
+	rng := rand.New(rand.NewSource(1))
+	p = RetryPolicy{MaxAttempts: 10, Backoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	for i := 0; i < 100; i++ {
+		d := p.backoff(1, rng)
+		assert.True(t, d >= 50*time.Millisecond && d <= 100*time.Millisecond, d)
+		d = p.backoff(2, rng)
+		assert.True(t, d >= 100*time.Millisecond && d <= 200*time.Millisecond, d)
+		d = p.backoff(9, rng)
+		assert.True(t, d >= 150*time.Millisecond && d <= 300*time.Millisecond, d)
+	}
+	assert.Equal(t, time.Duration(0), RetryPolicy{MaxAttempts: 2}.backoff(1, rng))
+	assert.True(t, RetryPolicy{Backoff: time.Second}.backoff(100, rng) > 0)
+}
+
+func TestRun_retry(t *testing.T) {
+	// every other search is rejected, so every request succeeds on its
+	// second attempt
+	var calls int32
+	f := newFakeMilvus(t, func(method string, _ []byte) ([]byte, error) {
+		if method == searchMethod && atomic.AddInt32(&calls, 1)%2 == 1 {
+			return nil, status.Error(codes.Unavailable, "proxy is restarting")
+		}
+		return nil, nil
+	})
+	cfg := testConfig(f.addr)
+	cfg.Parallel = 1
+	cfg.Total = 5
+	cfg.Retry = RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+	r, err := Run(context.Background(), cfg, Queries{{1, 2}})
+	assert.Nil(t, err)
+	assert.Equal(t, 10, f.count(searchMethod))
+	assert.Equal(t, 5, r.Successful)
+	assert.Equal(t, &RetryStats{
+		MaxAttempts:             3,
+		Attempts:                []int{0, 5},
+		Retried:                 5,
+		Retries:                 5,
+		Recovered:               5,
+		FirstAttemptMean:        r.Retries.FirstAttemptMean,
+		FirstAttemptMax:         r.Retries.FirstAttemptMax,
+		FirstAttemptPercentiles: r.Retries.FirstAttemptPercentiles,
+	}, r.Retries)
+	assert.True(t, r.Retries.FirstAttemptMax > 0)
+	assert.True(t, r.Max > r.Retries.FirstAttemptMax)
+	text := &strings.Builder{}
+	_, err = r.WriteTextTo(text)
+	assert.Nil(t, err)
+	assert.Contains(t, text.String(), "Retries\nMax attempts: 3\nRetried: 5\nRetries: 5\nRecovered: 5\nFinal failures: 0\n")
+	assert.Equal(t, []int{0, 5}, r.toJSON().Retries.Attempts)
+	assert.Contains(t, r.toJSON().Retries.FirstAttemptLatencies, "p99")
+}
+
+func TestRun_retryEndpoints(t *testing.T) {
+	// retries go round-robin to the other endpoint, every failed attempt
+	// counts against the endpoint it was sent to
+	a := newFakeMilvus(t, nil)
+	b := newFakeMilvus(t, func(method string, _ []byte) ([]byte, error) {
+		if method == searchMethod {
+			return nil, status.Error(codes.Unavailable, "proxy is down")
+		}
+		return nil, nil
+	})
+	cfg := testConfig(a.addr + "," + b.addr)
+	cfg.Parallel = 1
+	cfg.Retry = RetryPolicy{MaxAttempts: 2}
+	r, err := Run(context.Background(), cfg, Queries{{1, 2}})
+	assert.Nil(t, err)
+	assert.Equal(t, cfg.Total, r.Successful)
+	assert.Equal(t, 2, len(r.Endpoints))
+	assert.Equal(t, a.count(searchMethod), r.Endpoints[0].Successful)
+	assert.Equal(t, 0, r.Endpoints[0].Failed)
+	assert.Equal(t, 0, r.Endpoints[1].Successful)
+	assert.Equal(t, b.count(searchMethod), r.Endpoints[1].Failed)
+	assert.True(t, r.Endpoints[1].Failed > 0)
+}
+
+func TestRun_retryExhausted(t *testing.T) {
+	f := newFakeMilvus(t, func(method string, _ []byte) ([]byte, error) {
+		if method == searchMethod {
+			return nil, status.Error(codes.ResourceExhausted, "rate limited")
+		}
+		return nil, nil
+	})
+	cfg := testConfig(f.addr)
+	cfg.Retry = RetryPolicy{MaxAttempts: 2}
+	r, err := Run(context.Background(), cfg, Queries{{1, 2}})
+	assert.Nil(t, err)
+	assert.Equal(t, 2*cfg.Total, f.count(searchMethod))
+	assert.Equal(t, cfg.Total, r.Failed)
+	assert.Equal(t, cfg.Total, r.Retries.FinalFailures)
+	assert.Equal(t, []int{0, cfg.Total}, r.Retries.Attempts)
+
+	// errors that are not retryable still stop the run
+	f = newFakeMilvus(t, func(method string, _ []byte) ([]byte, error) {
+		if method == searchMethod {
+			return nil, status.Error(codes.InvalidArgument, "bad params")
+		}
+		return nil, nil
+	})
+	cfg = testConfig(f.addr)
+	cfg.Parallel = 1
+	cfg.Retry = RetryPolicy{MaxAttempts: 2}
+	_, err = Run(context.Background(), cfg, Queries{{1, 2}})
+	assert.Error(t, err)
+	assert.Equal(t, 1, f.count(searchMethod))
+}
+
+func TestRetryRecord_merge(t *testing.T) {
+	a, b := &retryRecord{}, &retryRecord{}
+	a.add(time.Millisecond, 1, nil)
+	b.add(2*time.Millisecond, 3, errors.New("failed"))
+	b.add(3*time.Millisecond, 2, nil)
+	a.merge(b)
+	s := a.stats(Config{Retry: RetryPolicy{MaxAttempts: 3}}, []float64{50})
+	assert.Equal(t, []int{1, 1, 1}, s.Attempts)
+	assert.Equal(t, 2, s.Retried)
+	assert.Equal(t, 3, s.Retries)
+	assert.Equal(t, 1, s.Recovered)
+	assert.Equal(t, 1, s.FinalFailures)
+	assert.Equal(t, 2*time.Millisecond, s.FirstAttemptMean)
+	assert.Equal(t, []time.Duration{2 * time.Millisecond}, s.FirstAttemptPercentiles)
+	assert.Nil(t, (*retryRecord)(nil).stats(Config{}, nil))
+}
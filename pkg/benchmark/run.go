@@ -0,0 +1,134 @@
+package benchmark
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	milvusClient "github.com/xiaocai2333/milvus-sdk-go/v2/client"
+)
+
+// ErrAssertionsFailed is returned by Run, together with the Results, when
+// any assertion of the run failed.
+var ErrAssertionsFailed = errors.New("assertions failed")
+
+// Runner runs benchmarks and keeps a connection per Milvus address open
+// between them, so that a long running process does not pay for connecting
+// to Milvus on every run. It is safe for concurrent use.
+type Runner struct {
+	m       sync.Mutex
+	clients map[connectionKey]milvusClient.Client
+}
+
+// connectionKey tells apart the connections to the same address made with
+// different credentials.
+type connectionKey struct {
+	Origin     string
+	Connection Connection
+}
+
+func NewRunner() *Runner {
+	return &Runner{clients: map[connectionKey]milvusClient.Client{}}
+}
+
+func (r *Runner) client(ctx context.Context, origin string, conn Connection) (milvusClient.Client, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	key := connectionKey{Origin: origin, Connection: conn}
+	if c, ok := r.clients[key]; ok {
+		return c, nil
+	}
+	c, err := dial(ctx, origin, conn)
+	if err != nil {
+		return nil, err
+	}
+	r.clients[key] = c
+	return c, nil
+}
+
+// Close closes the connections to Milvus.
+func (r *Runner) Close() error {
+	r.m.Lock()
+	defer r.m.Unlock()
+	var err error
+	for key, c := range r.clients {
+		if closeErr := c.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		delete(r.clients, key)
+	}
+	return err
+}
+
+// Run benchmarks searches with the vectors of src as described by cfg, on
+// Milvus or, if cfg.Agents is set, on agents. If cfg.Replay is set, the
+// recorded requests are sent instead of those of src. The results are checked
+// against the assertions of cfg and then written to sinks in order. Run
+// fails if cfg is invalid, Milvus cannot be reached, a search fails with an
+// error cfg.Retry does not retry or ctx is done before the run finished.
+func Run(ctx context.Context, cfg Config, src QuerySource, sinks ...Sink) (Results, error) {
+	r := NewRunner()
+	defer r.Close()
+	return r.Run(ctx, cfg, src, sinks...)
+}
+
+// Run is the same as the package level Run, reusing the connections of r.
+func (r *Runner) Run(ctx context.Context, cfg Config, src QuerySource, sinks ...Sink) (Results, error) {
+	if err := cfg.Validate(); err != nil {
+		return Results{}, err
+	}
+	assertions, err := parseAssertions(cfg.assertions())
+	if err != nil {
+		return Results{}, err
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = newSeed()
+	}
+	if cfg.Replay != "" {
+		w, err := readWorkload(cfg.Replay)
+		if err != nil {
+			return Results{}, err
+		}
+		cfg, src = w.configure(cfg), w
+		if _, err := cfg.consistencyLevel(); err != nil {
+			return Results{}, err
+		}
+	}
+
+	var out Results
+	if cfg.Agents > 0 {
+		queries, ok := src.(Queries)
+		if !ok {
+			return Results{}, errors.New("a distributed run needs Queries as its query source")
+		}
+		out, err = coordinate(ctx, cfg, queries)
+	} else {
+		var eps []endpoint
+		if cfg.Calibrate {
+			var close func()
+			eps, close, err = calibrationEndpoints(ctx)
+			if err != nil {
+				return Results{}, err
+			}
+			defer close()
+		} else if eps, err = endpoints(ctx, cfg, r.client); err != nil {
+			return Results{}, err
+		}
+		out, err = benchmark(ctx, cfg, eps, src)
+	}
+	if err != nil {
+		return Results{}, err
+	}
+
+	passed := checkAssertions(&out, assertions)
+	for _, sink := range sinks {
+		if err := sink.Write(out); err != nil {
+			return out, err
+		}
+	}
+	if !passed {
+		return out, ErrAssertionsFailed
+	}
+	return out, nil
+}
@@ -0,0 +1,153 @@
+package benchmark
+
+import (
+	"os"
+	"runtime"
+	"time"
+)
+
+// Version of the benchmarker, set at build time with
+// -ldflags "-X github.com/zilliztech/milvus_benchmark/milvus_benchmark/benchmarker/pkg/benchmark.Version=v0.1.0"
+var Version = "dev"
+
+// RunInfo describes where and how a run was produced, so that a result file
+// can be interpreted on its own.
+type RunInfo struct {
+	Config     Config
+	Server     ServerInfo
+	Hostname   string
+	GoVersion  string
+	GOMAXPROCS int
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+func newRunInfo(cfg Config, server ServerInfo, started, finished time.Time) RunInfo {
+	hostname, err := os.Hostname()
+	if err != nil {
+		infof("failed to get hostname: %s", err)
+	}
+	return RunInfo{
+		Config:     cfg,
+		Server:     server,
+		Hostname:   hostname,
+		GoVersion:  runtime.Version(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		StartedAt:  started,
+		FinishedAt: finished,
+	}
+}
+
+type resultsJSONRun struct {
+	Config       resultsJSONConfig `json:"config"`
+	SearchParams SearchParams      `json:"search_params"`
+	Server       resultsJSONServer `json:"server"`
+	Client       resultsJSONClient `json:"client"`
+	StartedAt    time.Time         `json:"started_at"`
+	FinishedAt   time.Time         `json:"finished_at"`
+}
+
+type resultsJSONConfig struct {
+	Name         string `json:"name"`
+	Mode         string `json:"mode"`
+	Origin       string `json:"origin"`
+	Nq           int    `json:"nq"`
+	Parallel     int    `json:"parallel"`
+	Total        int    `json:"total"`
+	QueryFile    string `json:"query_file"`
+	QueryDataset string `json:"query_dataset,omitempty"`
+	QueryOffset  int    `json:"query_offset"`
+	QueryRows    int    `json:"query_rows"`
+	// ConsistencyLevel and GuaranteeTimestamp are those the searches were
+	// sent with.
+	ConsistencyLevel   string `json:"consistency_level"`
+	GuaranteeTimestamp uint64 `json:"guarantee_timestamp"`
+	Balance            string `json:"balance,omitempty"`
+	BalanceScope       string `json:"balance_scope,omitempty"`
+	Seed               int64  `json:"seed"`
+	Calibration        bool   `json:"calibration,omitempty"`
+	// Replay is the workload the run sent again and ReplaySpeed its pace,
+	// 0 for as fast as possible.
+	Replay      string   `json:"replay,omitempty"`
+	ReplaySpeed *float64 `json:"replay_speed,omitempty"`
+	ThinkTime   string   `json:"think_time,omitempty"`
+	TLS         bool     `json:"tls"`
+	Username    string   `json:"username,omitempty"`
+}
+
+type resultsJSONServer struct {
+	Version  string      `json:"version"`
+	RowCount int64       `json:"row_count"`
+	Fields   []FieldInfo `json:"fields"`
+}
+
+type resultsJSONClient struct {
+	Version    string `json:"version"`
+	GoVersion  string `json:"go_version"`
+	GOMAXPROCS int    `json:"gomaxprocs"`
+	Hostname   string `json:"hostname"`
+}
+
+// toJSON returns nil for results that were not produced by a run, e.g. the
+// ones rebuilt in tests.
+func (r RunInfo) toJSON() *resultsJSONRun {
+	if r.StartedAt.IsZero() {
+		return nil
+	}
+	queryFile := r.Config.QueryFile
+	if _, err := os.Stat(queryFile); err != nil {
+		// inline json vectors are not worth repeating
+		queryFile = "<inline>"
+	}
+	var balance, balanceScope string
+	if len(r.Config.origins()) > 1 {
+		balance, balanceScope = r.Config.Balance, r.Config.BalanceScope
+		if balance == "" {
+			balance = BalanceRoundRobin
+		}
+		if balanceScope == "" {
+			balanceScope = BalancePerRequest
+		}
+	}
+	var replaySpeed *float64
+	if r.Config.Replay != "" {
+		replaySpeed = &r.Config.ReplaySpeed
+	}
+	level, _ := r.Config.consistencyLevel()
+	guaranteeTimestamp, _ := r.Config.guaranteeTimestamp()
+	return &resultsJSONRun{
+		Config: resultsJSONConfig{
+			Name:               r.Config.CaseName(),
+			Mode:               r.Config.Mode,
+			Origin:             r.Config.Origin,
+			Nq:                 r.Config.Nq,
+			Parallel:           r.Config.Parallel,
+			Total:              r.Config.Total,
+			QueryFile:          queryFile,
+			QueryDataset:       r.Config.QueryDataset,
+			QueryOffset:        r.Config.QueryOffset,
+			QueryRows:          r.Config.QueryRows,
+			ConsistencyLevel:   level,
+			GuaranteeTimestamp: guaranteeTimestamp,
+			Balance:            balance,
+			BalanceScope:       balanceScope,
+			Seed:               r.Config.Seed,
+			Calibration:        r.Config.Calibrate,
+			Replay:             r.Config.Replay,
+			ReplaySpeed:        replaySpeed,
+			ThinkTime:          r.Config.ThinkTime,
+			TLS:                r.Config.Connection.tlsEnabled(),
+			Username:           r.Config.Connection.Username,
+		},
+		SearchParams: r.Config.SearchParams,
+		Server:       resultsJSONServer(r.Server),
+		Client: resultsJSONClient{
+			Version:    Version,
+			GoVersion:  r.GoVersion,
+			GOMAXPROCS: r.GOMAXPROCS,
+			Hostname:   r.Hostname,
+		},
+		StartedAt:  r.StartedAt,
+		FinishedAt: r.FinishedAt,
+	}
+}
@@ -0,0 +1,58 @@
+package benchmark
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestServerVersion(t *testing.T) {
+	milvus := newFakeMilvus(t, func(method string, req []byte) ([]byte, error) {
+		assert.Equal(t, getMetricsMethod, method)
+		assert.Contains(t, string(req), "system_info")
+		resp := protowire.AppendTag(nil, 3, protowire.BytesType)
+		resp = protowire.AppendString(resp, "proxy")
+		resp = protowire.AppendTag(resp, 2, protowire.BytesType)
+		resp = protowire.AppendString(resp, `{"nodes_info": [{"infos": {"system_info": {"build_version": "v2.0.2"}}}]}`)
+		return resp, nil
+	})
+
+	version, err := serverVersion(context.Background(), milvus.addr, []grpc.DialOption{grpc.WithInsecure()})
+	assert.Nil(t, err)
+	assert.Equal(t, "v2.0.2", version)
+}
+
+func TestWriteJsonTo_run(t *testing.T) {
+	r := testResults()
+	b := &strings.Builder{}
+	_, err := r.WriteJsonTo(b)
+	assert.Nil(t, err)
+	assert.NotContains(t, b.String(), `"run"`)
+
+	cfg := Config{Mode: "locust", Origin: "localhost:19530", Parallel: 4, Total: 102, QueryFile: "[[1, 2]]"}
+	cfg.CollectionName = "test"
+	cfg.Limit = 10
+	started := time.Date(2022, 7, 11, 0, 0, 0, 0, time.UTC)
+	r.Run = newRunInfo(cfg, ServerInfo{Version: "v2.0.2", RowCount: 1000}, started, started.Add(r.Took))
+
+	b.Reset()
+	_, err = r.WriteJsonTo(b)
+	assert.Nil(t, err)
+	var obj resultsJSON
+	assert.Nil(t, json.Unmarshal([]byte(b.String()), &obj))
+	run := obj.Metadata.Run
+	assert.NotNil(t, run)
+	assert.Equal(t, "<inline>", run.Config.QueryFile)
+	assert.Equal(t, "test", run.SearchParams.CollectionName)
+	assert.Equal(t, 10, run.SearchParams.Limit)
+	assert.Equal(t, "v2.0.2", run.Server.Version)
+	assert.Equal(t, int64(1000), run.Server.RowCount)
+	assert.Equal(t, Version, run.Client.Version)
+	assert.Equal(t, started.Add(time.Second), run.FinishedAt)
+}
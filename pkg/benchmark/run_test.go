@@ -0,0 +1,106 @@
+package benchmark
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const searchMethod = "/milvus.proto.milvus.MilvusService/Search"
+
+func testConfig(addr string) Config {
+	cfg := Config{Mode: "locust", Origin: addr, Parallel: 2, Total: 10}
+	cfg.CollectionName = "test"
+	cfg.IndexType = "HNSW"
+	cfg.Params.Ef = 64
+	cfg.Limit = 10
+	return cfg
+}
+
+func TestMain(m *testing.M) {
+	SetOutput(io.Discard)
+	m.Run()
+}
+
+func TestRun(t *testing.T) {
+	milvus := newFakeMilvus(t, nil)
+	cfg := testConfig(milvus.addr)
+
+	var sunk []Results
+	b := &strings.Builder{}
+	r, err := Run(context.Background(), cfg, Queries{{1, 2}}, WriterSink(b, FormatCSV),
+		SinkFunc(func(r Results) error {
+			sunk = append(sunk, r)
+			return nil
+		}))
+	assert.Nil(t, err)
+	assert.Equal(t, 10, r.Successful)
+	assert.Equal(t, 0, r.Failed)
+	assert.Equal(t, 10, milvus.count(searchMethod))
+	assert.Equal(t, "test", r.Run.Config.CollectionName)
+	assert.Equal(t, []Results{r}, sunk)
+	assert.True(t, strings.HasPrefix(b.String(), "case,total,"))
+
+	cfg.Assert = []string{"qps<0"}
+	r, err = Run(context.Background(), cfg, Queries{{1, 2}}, SinkFunc(func(r Results) error {
+		sunk = append(sunk, r)
+		return nil
+	}))
+	assert.Equal(t, ErrAssertionsFailed, err)
+	assert.Equal(t, 2, len(sunk))
+	assert.False(t, r.Assertions[0].Passed)
+}
+
+func TestRun_errors(t *testing.T) {
+	milvus := newFakeMilvus(t, func(method string, _ []byte) ([]byte, error) {
+		if method == searchMethod {
+			return nil, status.Error(codes.Unavailable, "node down")
+		}
+		return nil, nil
+	})
+	cfg := testConfig(milvus.addr)
+
+	_, err := Run(context.Background(), cfg, Queries{{1, 2}})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.True(t, milvus.count(searchMethod) <= cfg.Parallel)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Run(ctx, testConfig(newFakeMilvus(t, nil).addr), Queries{{1, 2}})
+	assert.Error(t, err)
+
+	invalid := cfg
+	invalid.Origin = ""
+	_, err = Run(context.Background(), invalid, Queries{{1, 2}})
+	assert.Error(t, err)
+
+	invalid = cfg
+	invalid.IndexType = "DISKANN"
+	_, err = Run(context.Background(), invalid, Queries{{1, 2}})
+	assert.Error(t, err)
+
+	for _, total := range []int{0, -1} {
+		invalid = cfg
+		invalid.Total = total
+		_, err = Run(context.Background(), invalid, Queries{{1, 2}})
+		assert.Error(t, err)
+	}
+
+	distributed := cfg
+	distributed.Agents = 2
+	_, err = Run(context.Background(), distributed, QuerySourceFunc(Queries{{1, 2}}.Next))
+	assert.Error(t, err)
+
+	sinkErr := io.ErrShortWrite
+	_, err = Run(context.Background(), testConfig(newFakeMilvus(t, nil).addr), Queries{{1, 2}},
+		SinkFunc(func(Results) error { return sinkErr }))
+	assert.Equal(t, sinkErr, err)
+	_, err = Run(context.Background(), testConfig(newFakeMilvus(t, nil).addr), Queries{{1, 2}},
+		WriterSink(io.Discard, "yaml"))
+	assert.Error(t, err)
+}
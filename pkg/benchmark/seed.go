@@ -0,0 +1,34 @@
+package benchmark
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"time"
+)
+
+// newSeed returns the seed of a run not given one, it is recorded in the
+// results so that the run can be repeated.
+func newSeed() int64 {
+	if seed := time.Now().UnixNano(); seed != 0 {
+		return seed
+	}
+	return 1
+}
+
+// seedStream derives the seed of the random stream named name and numbered
+// i from the seed of the run. The streams are independent of each other and
+// of the order they are used in, which keeps the requests of a worker the
+// same however the workers are scheduled.
+func seedStream(seed int64, name string, i int) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	// splitmix64, so that neighbouring streams are not correlated
+	x := (uint64(seed) ^ h.Sum64()) + uint64(i+1)*0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return int64(x ^ (x >> 31))
+}
+
+func newStream(seed int64, name string, i int) *rand.Rand {
+	return rand.New(rand.NewSource(seedStream(seed, name, i)))
+}
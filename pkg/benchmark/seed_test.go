@@ -0,0 +1,61 @@
+package benchmark
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeedStream(t *testing.T) {
+	assert.Equal(t, seedStream(42, "worker", 0), seedStream(42, "worker", 0))
+	assert.NotEqual(t, seedStream(42, "worker", 0), seedStream(42, "worker", 1))
+	assert.NotEqual(t, seedStream(42, "worker", 0), seedStream(43, "worker", 0))
+	assert.NotEqual(t, seedStream(42, "worker", 0), seedStream(42, "agent", 0))
+	assert.NotEqual(t, int64(0), newSeed())
+}
+
+func TestRun_seed(t *testing.T) {
+	var m sync.Mutex
+	var sequence []string
+	handle := func(name string) func(string, []byte) ([]byte, error) {
+		return func(method string, _ []byte) ([]byte, error) {
+			if method == searchMethod {
+				m.Lock()
+				sequence = append(sequence, name)
+				m.Unlock()
+			}
+			return nil, nil
+		}
+	}
+	a, b := newFakeMilvus(t, handle("a")), newFakeMilvus(t, handle("b"))
+	run := func(seed int64) ([]string, Results) {
+		sequence = nil
+		cfg := testConfig(a.addr + "," + b.addr)
+		cfg.Parallel = 1
+		cfg.Total = 20
+		cfg.Balance = BalanceRandom
+		cfg.Seed = seed
+		r, err := Run(context.Background(), cfg, Queries{{1, 2}})
+		assert.Nil(t, err)
+		return sequence, r
+	}
+
+	first, r := run(7)
+	assert.Equal(t, int64(7), r.Run.toJSON().Config.Seed)
+	second, _ := run(7)
+	assert.Equal(t, first, second)
+	other, _ := run(8)
+	assert.NotEqual(t, first, other)
+
+	_, r = run(0)
+	assert.NotEqual(t, int64(0), r.Run.Config.Seed)
+}
+
+func TestAgentJobs_seed(t *testing.T) {
+	cfg := Config{Total: 10, Parallel: 2, Seed: 5}
+	jobs := agentJobs(cfg, nil, 2, cfg.StartAt)
+	assert.NotEqual(t, jobs[0].Config.Seed, jobs[1].Config.Seed)
+	assert.Equal(t, jobs[0].Config.Seed, agentJobs(cfg, nil, 2, cfg.StartAt)[0].Config.Seed)
+}
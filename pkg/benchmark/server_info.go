@@ -0,0 +1,151 @@
+package benchmark
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type ServerInfo struct {
+	Version  string
+	RowCount int64
+	Fields   []FieldInfo
+}
+
+type FieldInfo struct {
+	Name       string            `json:"name"`
+	DataType   string            `json:"data_type"`
+	PrimaryKey bool              `json:"primary_key"`
+	AutoID     bool              `json:"auto_id"`
+	TypeParams map[string]string `json:"type_params,omitempty"`
+}
+
+// describeServer collects what the SDK can tell about the collection under
+// test. It is best effort, a failure is reported but never fails the run.
+func describeServer(ctx context.Context, e endpoint, cfg Config, opts []grpc.DialOption) ServerInfo {
+	var info ServerInfo
+	coll, err := e.client.DescribeCollection(ctx, cfg.CollectionName)
+	if err != nil {
+		infof("failed to describe collection %q: %s", cfg.CollectionName, err)
+	} else if coll.Schema != nil {
+		for _, f := range coll.Schema.Fields {
+			info.Fields = append(info.Fields, FieldInfo{
+				Name:       f.Name,
+				DataType:   f.DataType.Name(),
+				PrimaryKey: f.PrimaryKey,
+				AutoID:     f.AutoID,
+				TypeParams: f.TypeParams,
+			})
+		}
+	}
+
+	stats, err := e.client.GetCollectionStatistics(ctx, cfg.CollectionName)
+	if err != nil {
+		infof("failed to get statistics of collection %q: %s", cfg.CollectionName, err)
+	} else if rowCount, ok := stats["row_count"]; ok {
+		info.RowCount, _ = strconv.ParseInt(rowCount, 10, 64)
+	}
+
+	info.Version, err = serverVersion(ctx, e.origin, opts)
+	if err != nil {
+		infof("failed to get server version: %s", err)
+	}
+	return info
+}
+
+const getMetricsMethod = "/milvus.proto.milvus.MilvusService/GetMetrics"
+
+// rawCodec passes already encoded messages through, it lets us call
+// GetMetrics which the SDK does not expose.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	return *(v.(*[]byte)), nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	*(v.(*[]byte)) = append([]byte{}, data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+func (rawCodec) String() string {
+	return "proto"
+}
+
+// serverVersion asks the proxy for its system info and returns the first
+// build_version found in it.
+func serverVersion(ctx context.Context, origin string, opts []grpc.DialOption) (string, error) {
+	conn, err := grpc.DialContext(ctx, origin, opts...)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	// GetMetricsRequest{request: 2}
+	req := protowire.AppendTag(nil, 2, protowire.BytesType)
+	req = protowire.AppendString(req, `{"metric_type": "system_info"}`)
+	var resp []byte
+	callCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := conn.Invoke(callCtx, getMetricsMethod, &req, &resp, grpc.ForceCodec(rawCodec{})); err != nil {
+		return "", err
+	}
+
+	// GetMetricsResponse{response: 2}
+	var metrics string
+	for len(resp) > 0 {
+		num, typ, n := protowire.ConsumeTag(resp)
+		if n < 0 {
+			return "", protowire.ParseError(n)
+		}
+		resp = resp[n:]
+		if num == 2 && typ == protowire.BytesType {
+			metrics, n = protowire.ConsumeString(resp)
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, resp)
+		}
+		if n < 0 {
+			return "", protowire.ParseError(n)
+		}
+		resp = resp[n:]
+	}
+
+	var obj interface{}
+	if err := json.Unmarshal([]byte(metrics), &obj); err != nil {
+		return "", errors.Wrap(err, "parse system info")
+	}
+	if version, ok := findString(obj, "build_version"); ok {
+		return version, nil
+	}
+	return "", errors.Errorf("build_version not found in system info")
+}
+
+func findString(obj interface{}, key string) (string, bool) {
+	switch v := obj.(type) {
+	case map[string]interface{}:
+		if s, ok := v[key].(string); ok && s != "" {
+			return s, true
+		}
+		for _, child := range v {
+			if s, ok := findString(child, key); ok {
+				return s, true
+			}
+		}
+	case []interface{}:
+		for _, child := range v {
+			if s, ok := findString(child, key); ok {
+				return s, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,63 @@
+package benchmark
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Sink receives the Results of a run.
+type Sink interface {
+	Write(r Results) error
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(r Results) error
+
+func (f SinkFunc) Write(r Results) error {
+	return f(r)
+}
+
+// Output formats of WriterSink.
+const (
+	FormatText     = "text"
+	FormatJSON     = "json"
+	FormatCSV      = "csv"
+	FormatMarkdown = "markdown"
+)
+
+// WriterSink writes the results to w in format.
+func WriterSink(w io.Writer, format string) Sink {
+	return SinkFunc(func(r Results) error {
+		var err error
+		switch format {
+		case FormatText:
+			_, err = r.WriteTextTo(w)
+		case FormatJSON:
+			_, err = r.WriteJsonTo(w)
+		case FormatCSV:
+			_, err = r.WriteCsvTo(w)
+		case FormatMarkdown:
+			_, err = r.WriteMarkdownTo(w)
+		default:
+			err = errors.Errorf("unsupported output format %q, must be one of [%s, %s, %s, %s]",
+				format, FormatText, FormatJSON, FormatCSV, FormatMarkdown)
+		}
+		return err
+	})
+}
+
+// HistorySink appends the results to the history file fname.
+func HistorySink(fname string) Sink {
+	return SinkFunc(func(r Results) error {
+		return appendHistory(fname, r.Run.Config, r)
+	})
+}
+
+// ReportSink writes an html report of the results to fname, with the sweeps
+// of the runs recorded in historyFile if it is set.
+func ReportSink(fname, historyFile string) Sink {
+	return SinkFunc(func(r Results) error {
+		return writeReport(fname, r, historyFile)
+	})
+}
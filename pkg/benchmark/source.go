@@ -0,0 +1,69 @@
+package benchmark
+
+import (
+	"os"
+	"strings"
+
+	"github.com/xiaocai2333/milvus-sdk-go/v2/entity"
+)
+
+// QuerySource provides the query vectors of the search requests of a run.
+type QuerySource interface {
+	// Next returns the vectors of the next request, it is called Total
+	// times before the first request is sent.
+	Next() []entity.Vector
+}
+
+// QuerySourceFunc adapts a function to a QuerySource.
+type QuerySourceFunc func() []entity.Vector
+
+func (f QuerySourceFunc) Next() []entity.Vector {
+	return f()
+}
+
+// Queries is a QuerySource sending all of its vectors with every request.
+// It is the only source a distributed run accepts, as it is sent to the
+// agents.
+type Queries [][]float32
+
+func (q Queries) Next() []entity.Vector {
+	vectors := make([]entity.Vector, 0, len(q))
+	for _, query := range q {
+		vectors = append(vectors, entity.FloatVector(query))
+	}
+	return vectors
+}
+
+// ReadQueries reads the rows of cfg.QueryFile selected by cfg.QueryOffset
+// and cfg.QueryRows. The file may be .json, .hdf5, .npy, .fvecs or .bvecs,
+// if it does not exist cfg.QueryFile is parsed as inline json instead.
+func ReadQueries(cfg Config) (Queries, error) {
+	var q Queries
+	if _, err := os.Stat(cfg.QueryFile); err != nil {
+		// not a file, parse it as an inline json str
+		q, err = readJSONQueries(strings.NewReader(cfg.QueryFile))
+		if err != nil {
+			return nil, err
+		}
+		return sliceQueries(q, cfg.QueryOffset, cfg.QueryRows)
+	}
+
+	format, err := detectQueryFormat(cfg.QueryFile)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case queryFormatHDF5:
+		q, err = readHDF5Queries(cfg.QueryFile, cfg.QueryDataset)
+	case queryFormatNumpy:
+		q, err = readNumpyQueries(cfg.QueryFile)
+	case queryFormatVecs:
+		return readVecsQueries(cfg.QueryFile, cfg.QueryOffset, cfg.QueryRows)
+	default:
+		q, err = readJSONQueryFile(cfg.QueryFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sliceQueries(q, cfg.QueryOffset, cfg.QueryRows)
+}
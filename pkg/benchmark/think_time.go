@@ -0,0 +1,109 @@
+package benchmark
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Distributions of Config.ThinkTime.
+const (
+	ThinkConstant    = "constant"
+	ThinkUniform     = "uniform"
+	ThinkExponential = "exponential"
+)
+
+// thinkTime is a parsed Config.ThinkTime, such as "100ms", "constant:100ms",
+// "uniform:50ms-150ms" or "exponential:100ms". Mean is the pause of the
+// constant and the exponential distributions, min and max bound the uniform
+// one.
+type thinkTime struct {
+	distribution string
+	mean         time.Duration
+	min, max     time.Duration
+}
+
+func parseThinkTime(s string) (thinkTime, error) {
+	if s == "" {
+		return thinkTime{}, nil
+	}
+	t := thinkTime{distribution: ThinkConstant}
+	value := s
+	if i := strings.Index(s, ":"); i >= 0 {
+		t.distribution, value = s[:i], s[i+1:]
+	}
+	var err error
+	switch t.distribution {
+	case ThinkConstant, ThinkExponential:
+		t.mean, err = time.ParseDuration(value)
+		if err == nil && t.mean < 0 {
+			err = errors.New("negative duration")
+		}
+	case ThinkUniform:
+		bounds := strings.SplitN(value, "-", 2)
+		if len(bounds) != 2 {
+			return t, errors.Errorf("invalid think time %q, expected uniform:<min>-<max>", s)
+		}
+		if t.min, err = time.ParseDuration(bounds[0]); err == nil {
+			t.max, err = time.ParseDuration(bounds[1])
+		}
+		if err == nil && (t.min < 0 || t.max < t.min) {
+			err = errors.New("min must not be negative nor larger than max")
+		}
+	default:
+		return t, errors.Errorf("unsupported think time distribution %q, must be one of [%s, %s, %s]",
+			t.distribution, ThinkConstant, ThinkUniform, ThinkExponential)
+	}
+	return t, errors.Wrapf(err, "invalid think time %q", s)
+}
+
+func (t thinkTime) enabled() bool {
+	return t.mean > 0 || t.max > 0
+}
+
+// draw returns the next pause of a worker, drawn from its rng.
+func (t thinkTime) draw(rng *rand.Rand) time.Duration {
+	switch t.distribution {
+	case ThinkUniform:
+		return t.min + time.Duration(rng.Int63n(int64(t.max-t.min)+1))
+	case ThinkExponential:
+		return time.Duration(rng.ExpFloat64() * float64(t.mean))
+	}
+	return t.mean
+}
+
+// ThinkTimeStats are the pauses the workers made between their requests.
+// They are not part of the latencies, but of Took and so of the
+// throughput.
+type ThinkTimeStats struct {
+	// ThinkTime is Config.ThinkTime.
+	ThinkTime string
+	Pauses    int
+	Total     time.Duration
+}
+
+func (s *ThinkTimeStats) add(d time.Duration) {
+	s.Pauses++
+	s.Total += d
+}
+
+// Mean is the average pause.
+func (s *ThinkTimeStats) Mean() time.Duration {
+	if s.Pauses == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Pauses)
+}
+
+func (s *ThinkTimeStats) merge(other *ThinkTimeStats) {
+	s.ThinkTime = other.ThinkTime
+	s.Pauses += other.Pauses
+	s.Total += other.Total
+}
+
+func (s *ThinkTimeStats) String() string {
+	return fmt.Sprintf("Pause: %s\nPauses: %d\nMean: %s\n", s.ThinkTime, s.Pauses, s.Mean())
+}
@@ -0,0 +1,89 @@
+package benchmark
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseThinkTime(t *testing.T) {
+	for s, want := range map[string]thinkTime{
+		"":                   {},
+		"100ms":              {distribution: ThinkConstant, mean: 100 * time.Millisecond},
+		"constant:1s":        {distribution: ThinkConstant, mean: time.Second},
+		"exponential:20ms":   {distribution: ThinkExponential, mean: 20 * time.Millisecond},
+		"uniform:50ms-150ms": {distribution: ThinkUniform, min: 50 * time.Millisecond, max: 150 * time.Millisecond},
+	} {
+		got, err := parseThinkTime(s)
+		assert.Nil(t, err, s)
+		assert.Equal(t, want, got, s)
+	}
+	for _, s := range []string{"fast", "-1s", "uniform:1s", "uniform:2s-1s", "normal:1s"} {
+		_, err := parseThinkTime(s)
+		assert.Error(t, err, s)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	uniform, _ := parseThinkTime("uniform:50ms-150ms")
+	exponential, _ := parseThinkTime("exponential:10ms")
+	var sum time.Duration
+	for i := 0; i < 10000; i++ {
+		d := uniform.draw(rng)
+		assert.True(t, d >= 50*time.Millisecond && d <= 150*time.Millisecond, d)
+		sum += exponential.draw(rng)
+	}
+	mean := sum / 10000
+	assert.True(t, mean > 9*time.Millisecond && mean < 11*time.Millisecond, mean)
+}
+
+func TestRun_thinkTime(t *testing.T) {
+	f := newFakeMilvus(t, nil)
+	cfg := testConfig(f.addr)
+	cfg.ThinkTime = "constant:20ms"
+	r, err := Run(context.Background(), cfg, Queries{{1, 2}})
+	assert.Nil(t, err)
+
+	// 2 workers with 5 requests each pause 4 times
+	assert.Equal(t, &ThinkTimeStats{ThinkTime: "constant:20ms", Pauses: 8, Total: 160 * time.Millisecond}, r.ThinkTime)
+	assert.True(t, r.Took >= 80*time.Millisecond, r.Took)
+	assert.True(t, r.Max < 80*time.Millisecond, r.Max)
+	assert.True(t, r.QueriesPerSecond < 10/0.08, r.QueriesPerSecond)
+	assert.Equal(t, "constant:20ms", r.Run.toJSON().Config.ThinkTime)
+	assert.Equal(t, int64(20*time.Millisecond), r.toJSON().ThinkTime.Mean)
+	text := &strings.Builder{}
+	_, err = r.WriteTextTo(text)
+	assert.Nil(t, err)
+	assert.Contains(t, text.String(), "Think time\nPause: constant:20ms\nPauses: 8\nMean: 20ms\n")
+
+	cfg.Replay = "workload.bin"
+	assert.Error(t, cfg.Validate())
+}
+
+func TestRun_thinkTimeSeed(t *testing.T) {
+	// the same seed makes the same pauses whether a search is retried or not
+	pauses := func(fail bool) time.Duration {
+		var calls int32
+		f := newFakeMilvus(t, func(method string, _ []byte) ([]byte, error) {
+			if method == searchMethod && fail && atomic.AddInt32(&calls, 1) == 1 {
+				return nil, status.Error(codes.Unavailable, "proxy is restarting")
+			}
+			return nil, nil
+		})
+		cfg := testConfig(f.addr)
+		cfg.Parallel = 1
+		cfg.Seed = 7
+		cfg.ThinkTime = "exponential:1ms"
+		cfg.Retry = RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond}
+		r, err := Run(context.Background(), cfg, Queries{{1, 2}})
+		assert.Nil(t, err)
+		return r.ThinkTime.Total
+	}
+	assert.Equal(t, pauses(false), pauses(true))
+}
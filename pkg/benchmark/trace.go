@@ -0,0 +1,407 @@
+package benchmark
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/zilliztech/milvus_benchmark/milvus_benchmark/benchmarker/internal/numpy"
+)
+
+// Trace formats, told apart by the extension of Config.TraceOut.
+const (
+	TraceCSV    = "csv"
+	TraceNDJSON = "ndjson"
+	TraceNumpy  = "npy"
+)
+
+// traceBuffer is how many records the workers may be ahead of the trace
+// writer before they wait for it.
+const traceBuffer = 1 << 16
+
+// traceColumns are the columns of a trace, the npy format stores them as
+// int64 without the error text.
+var traceColumns = []string{"start_unix_ns", "worker", "op", "request", "nq", "latency_ns", "status", "error"}
+
+// traceOps numbers the operations in the npy format.
+var traceOps = []string{opSearch}
+
+// TraceRecord is a request of a run as written to Config.TraceOut.
+type TraceRecord struct {
+	Start   time.Time
+	Worker  int
+	Op      string
+	Request int
+	Nq      int
+	Latency time.Duration
+	Status  codes.Code
+	Error   string
+}
+
+func newTraceRecord(start time.Time, worker int, op string, req searchRequest, latency time.Duration, err error) TraceRecord {
+	r := TraceRecord{
+		Start:   start,
+		Worker:  worker,
+		Op:      op,
+		Request: req.seq,
+		Nq:      len(req.vectors),
+		Latency: latency,
+		Status:  status.Code(err),
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// traceFormat returns the format of the trace file fname.
+func traceFormat(fname string) (string, error) {
+	switch strings.ToLower(filepath.Ext(fname)) {
+	case ".csv":
+		return TraceCSV, nil
+	case ".ndjson", ".jsonl":
+		return TraceNDJSON, nil
+	case ".npy":
+		return TraceNumpy, nil
+	}
+	return "", errors.Errorf("unsupported trace file %q, must end in one of [.csv, .ndjson, .jsonl, .npy]", fname)
+}
+
+// traceEncoder writes the records of one format.
+type traceEncoder interface {
+	encode(r TraceRecord) error
+	close() error
+}
+
+// traceWriter writes the records sent by the workers from its own
+// goroutine. The workers only wait for the disk once traceBuffer records are
+// queued.
+type traceWriter struct {
+	records chan TraceRecord
+	done    chan error
+}
+
+// startTrace starts writing the records of a run to fname, it returns nil if
+// fname is empty.
+func startTrace(fname string) (*traceWriter, error) {
+	if fname == "" {
+		return nil, nil
+	}
+	enc, err := newTraceEncoder(fname)
+	if err != nil {
+		return nil, err
+	}
+	t := &traceWriter{
+		records: make(chan TraceRecord, traceBuffer),
+		done:    make(chan error, 1),
+	}
+	go func() {
+		var err error
+		for r := range t.records {
+			if err == nil {
+				err = enc.encode(r)
+			}
+		}
+		if closeErr := enc.close(); err == nil {
+			err = closeErr
+		}
+		t.done <- errors.Wrapf(err, "failed to write trace %q", fname)
+	}()
+	return t, nil
+}
+
+// add queues a record, it is a no-op on a nil traceWriter.
+func (t *traceWriter) add(r TraceRecord) {
+	if t != nil {
+		t.records <- r
+	}
+}
+
+// Close writes the queued records and closes the file, it must be called
+// once the workers are done.
+func (t *traceWriter) Close() error {
+	if t == nil {
+		return nil
+	}
+	close(t.records)
+	return <-t.done
+}
+
+func newTraceEncoder(fname string) (traceEncoder, error) {
+	format, err := traceFormat(fname)
+	if err != nil {
+		return nil, err
+	}
+	if format == TraceNumpy {
+		w, err := numpy.Create(fname, numpy.INT64, len(traceColumns)-1)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create trace %q", fname)
+		}
+		return npyTraceEncoder{w}, nil
+	}
+	f, err := os.Create(fname)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create trace %q", fname)
+	}
+	buf := bufio.NewWriter(f)
+	if format == TraceNDJSON {
+		return &ndjsonTraceEncoder{f: f, buf: buf, enc: json.NewEncoder(buf)}, nil
+	}
+	enc := &csvTraceEncoder{f: f, w: csv.NewWriter(buf)}
+	if err := enc.w.Write(traceColumns); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return enc, nil
+}
+
+type csvTraceEncoder struct {
+	f *os.File
+	w *csv.Writer
+}
+
+func (e *csvTraceEncoder) encode(r TraceRecord) error {
+	return e.w.Write([]string{
+		strconv.FormatInt(r.Start.UnixNano(), 10),
+		strconv.Itoa(r.Worker),
+		r.Op,
+		strconv.Itoa(r.Request),
+		strconv.Itoa(r.Nq),
+		strconv.FormatInt(int64(r.Latency), 10),
+		r.Status.String(),
+		r.Error,
+	})
+}
+
+func (e *csvTraceEncoder) close() error {
+	e.w.Flush()
+	err := e.w.Error()
+	if closeErr := e.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+type traceJSON struct {
+	Start   int64  `json:"start_unix_ns"`
+	Worker  int    `json:"worker"`
+	Op      string `json:"op"`
+	Request int    `json:"request"`
+	Nq      int    `json:"nq"`
+	Latency int64  `json:"latency_ns"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+type ndjsonTraceEncoder struct {
+	f   *os.File
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func (e *ndjsonTraceEncoder) encode(r TraceRecord) error {
+	return e.enc.Encode(traceJSON{
+		Start:   r.Start.UnixNano(),
+		Worker:  r.Worker,
+		Op:      r.Op,
+		Request: r.Request,
+		Nq:      r.Nq,
+		Latency: int64(r.Latency),
+		Status:  r.Status.String(),
+		Error:   r.Error,
+	})
+}
+
+func (e *ndjsonTraceEncoder) close() error {
+	err := e.buf.Flush()
+	if closeErr := e.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+type npyTraceEncoder struct {
+	w *numpy.MatrixWriter
+}
+
+func (e npyTraceEncoder) encode(r TraceRecord) error {
+	op := -1
+	for i, name := range traceOps {
+		if name == r.Op {
+			op = i
+		}
+	}
+	return e.w.WriteRow([]int64{r.Start.UnixNano(), int64(r.Worker), int64(op), int64(r.Request),
+		int64(r.Nq), int64(r.Latency), int64(r.Status)})
+}
+
+func (e npyTraceEncoder) close() error {
+	return e.w.Close()
+}
+
+// parseStatus is the reverse of codes.Code.String.
+func parseStatus(s string) (codes.Code, error) {
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		if c.String() == s {
+			return c, nil
+		}
+	}
+	return 0, errors.Errorf("unknown status %q", s)
+}
+
+// ReadTrace reads a trace written by a run with Config.TraceOut.
+func ReadTrace(fname string) ([]TraceRecord, error) {
+	format, err := traceFormat(fname)
+	if err != nil {
+		return nil, err
+	}
+	var records []TraceRecord
+	switch format {
+	case TraceCSV:
+		records, err = readCsvTrace(fname)
+	case TraceNDJSON:
+		records, err = readNdjsonTrace(fname)
+	case TraceNumpy:
+		records, err = readNpyTrace(fname)
+	}
+	return records, errors.Wrapf(err, "failed to read trace %q", fname)
+}
+
+func readCsvTrace(fname string) ([]TraceRecord, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(bufio.NewReader(f)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 || strings.Join(rows[0], ",") != strings.Join(traceColumns, ",") {
+		return nil, errors.Errorf("missing header %s", strings.Join(traceColumns, ","))
+	}
+	records := make([]TraceRecord, 0, len(rows)-1)
+	for i, row := range rows[1:] {
+		var ints [5]int64
+		for j, col := range []int{0, 1, 3, 4, 5} {
+			if ints[j], err = strconv.ParseInt(row[col], 10, 64); err != nil {
+				return nil, errors.Wrapf(err, "line %d", i+2)
+			}
+		}
+		code, err := parseStatus(row[6])
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", i+2)
+		}
+		records = append(records, TraceRecord{
+			Start:   time.Unix(0, ints[0]),
+			Worker:  int(ints[1]),
+			Op:      row[2],
+			Request: int(ints[2]),
+			Nq:      int(ints[3]),
+			Latency: time.Duration(ints[4]),
+			Status:  code,
+			Error:   row[7],
+		})
+	}
+	return records, nil
+}
+
+func readNdjsonTrace(fname string) ([]TraceRecord, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var records []TraceRecord
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for {
+		var r traceJSON
+		if err := dec.Decode(&r); err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "record %d", len(records)+1)
+		}
+		code, err := parseStatus(r.Status)
+		if err != nil {
+			return nil, errors.Wrapf(err, "record %d", len(records)+1)
+		}
+		records = append(records, TraceRecord{
+			Start:   time.Unix(0, r.Start),
+			Worker:  r.Worker,
+			Op:      r.Op,
+			Request: r.Request,
+			Nq:      r.Nq,
+			Latency: time.Duration(r.Latency),
+			Status:  code,
+			Error:   r.Error,
+		})
+	}
+}
+
+func readNpyTrace(fname string) ([]TraceRecord, error) {
+	obj, err := numpy.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+	rows, err := obj.ReadInt64Matrix()
+	if err != nil {
+		return nil, err
+	}
+	records := make([]TraceRecord, 0, len(rows))
+	for i, row := range rows {
+		if len(row) != len(traceColumns)-1 {
+			return nil, errors.Errorf("expected %d columns, got %d", len(traceColumns)-1, len(row))
+		}
+		if row[2] < 0 || row[2] >= int64(len(traceOps)) {
+			return nil, errors.Errorf("unknown op %d in row %d", row[2], i)
+		}
+		records = append(records, TraceRecord{
+			Start:   time.Unix(0, row[0]),
+			Worker:  int(row[1]),
+			Op:      traceOps[row[2]],
+			Request: int(row[3]),
+			Nq:      int(row[4]),
+			Latency: time.Duration(row[5]),
+			Status:  codes.Code(row[6]),
+		})
+	}
+	return records, nil
+}
+
+// AnalyzeTrace rebuilds the Results of a run from its trace, with the
+// percentiles of cfg. The run is taken to have lasted from the start of its
+// first request to the end of its last one.
+func AnalyzeTrace(cfg Config, records []TraceRecord) (Results, error) {
+	if err := cfg.validatePercentiles(); err != nil {
+		return Results{}, err
+	}
+	var times []time.Duration
+	var first, last time.Time
+	workers := map[int]bool{}
+	for i, r := range records {
+		if r.Status == codes.OK {
+			times = append(times, r.Latency)
+		}
+		workers[r.Worker] = true
+		if end := r.Start.Add(r.Latency); i == 0 || end.After(last) {
+			last = end
+		}
+		if i == 0 || r.Start.Before(first) {
+			first = r.Start
+		}
+	}
+	cfg.Total = len(records)
+	cfg.Parallel = len(workers)
+	return analyze(cfg, times, last.Sub(first)), nil
+}
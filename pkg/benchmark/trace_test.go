@@ -0,0 +1,103 @@
+package benchmark
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTraceFormat(t *testing.T) {
+	for fname, want := range map[string]string{
+		"a.csv": TraceCSV, "a.ndjson": TraceNDJSON, "a.JSONL": TraceNDJSON, "a.npy": TraceNumpy,
+	} {
+		format, err := traceFormat(fname)
+		assert.Nil(t, err)
+		assert.Equal(t, want, format)
+	}
+	_, err := traceFormat("a.txt")
+	assert.Error(t, err)
+
+	cfg := testConfig("localhost:19530")
+	cfg.TraceOut = "trace.txt"
+	assert.Error(t, cfg.Validate())
+	cfg.TraceOut = "trace.csv"
+	assert.Nil(t, cfg.Validate())
+	cfg.Agents = 1
+	assert.Error(t, cfg.Validate())
+}
+
+func TestRun_trace(t *testing.T) {
+	f := newFakeMilvus(t, nil)
+	for _, ext := range []string{".csv", ".ndjson", ".npy"} {
+		t.Run(ext, func(t *testing.T) {
+			cfg := testConfig(f.addr)
+			cfg.TraceOut = filepath.Join(t.TempDir(), "trace"+ext)
+			r, err := Run(context.Background(), cfg, Queries{{1, 2}})
+			assert.Nil(t, err)
+
+			records, err := ReadTrace(cfg.TraceOut)
+			assert.Nil(t, err)
+			assert.Equal(t, cfg.Total, len(records))
+			sort.Slice(records, func(a, b int) bool {
+				return records[a].Request < records[b].Request
+			})
+			for i, rec := range records {
+				assert.Equal(t, i, rec.Request)
+				assert.Equal(t, i%cfg.Parallel, rec.Worker)
+				assert.Equal(t, opSearch, rec.Op)
+				assert.Equal(t, 1, rec.Nq)
+				assert.Equal(t, codes.OK, rec.Status)
+				assert.True(t, rec.Latency > 0)
+				assert.False(t, rec.Start.Before(r.Run.StartedAt))
+			}
+
+			offline, err := AnalyzeTrace(cfg, records)
+			assert.Nil(t, err)
+			assert.Equal(t, r.Successful, offline.Successful)
+			assert.Equal(t, r.Parallelization, offline.Parallelization)
+			assert.Equal(t, r.Max, offline.Max)
+			assert.Equal(t, r.Percentiles, offline.Percentiles)
+			assert.True(t, offline.Took <= r.Took)
+		})
+	}
+}
+
+func TestRun_traceFailure(t *testing.T) {
+	f := newFakeMilvus(t, func(method string, _ []byte) ([]byte, error) {
+		if method == searchMethod {
+			return nil, status.Error(codes.Unavailable, "proxy is down")
+		}
+		return nil, nil
+	})
+	cfg := testConfig(f.addr)
+	cfg.Parallel = 1
+	cfg.TraceOut = filepath.Join(t.TempDir(), "trace.csv")
+	_, err := Run(context.Background(), cfg, Queries{{1, 2}})
+	assert.Error(t, err)
+
+	records, err := ReadTrace(cfg.TraceOut)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, codes.Unavailable, records[0].Status)
+	assert.Contains(t, records[0].Error, "proxy is down")
+
+	r, err := AnalyzeTrace(cfg, records)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, r.Total)
+	assert.Equal(t, 1, r.Failed)
+
+	invalid := cfg
+	invalid.Percentiles = []float64{-5}
+	invalid.PercentileMethod = PercentileLinear
+	_, err = AnalyzeTrace(invalid, records)
+	assert.Error(t, err)
+	invalid = cfg
+	invalid.PercentileMethod = "bogus"
+	_, err = AnalyzeTrace(invalid, records)
+	assert.Error(t, err)
+}
@@ -0,0 +1,290 @@
+package benchmark
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/xiaocai2333/milvus-sdk-go/v2/entity"
+)
+
+// A workload file, written by Config.Capture and read by Config.Replay,
+// starts with workloadMagic and the uvarint length of a json
+// workloadHeader. Every request follows as the uvarints of its offset from
+// the start of the run in nanoseconds, its worker, its index in the run and
+// its number of vectors, then every vector as a kind byte, the uvarint
+// length of its data and the data itself, little endian float32s or bytes.
+var workloadMagic = []byte("MBWL\x01")
+
+// maxWorkloadLen bounds the lengths and the workers of a workload, so that
+// a corrupt file fails instead of exhausting the memory.
+const maxWorkloadLen = 1 << 24
+
+// Kinds of the vectors of a workload.
+const (
+	workloadFloatVector  byte = 0
+	workloadBinaryVector byte = 1
+)
+
+type workloadHeader struct {
+	SearchParams SearchParams `json:"search_params"`
+}
+
+// workload is a recorded run, it is the QuerySource of its replay.
+type workload struct {
+	params   SearchParams
+	requests []searchRequest
+	workers  int
+	next     int
+}
+
+// Next returns the vectors of the requests in the order they were sent.
+func (w *workload) Next() []entity.Vector {
+	v := w.requests[w.next%len(w.requests)].vectors
+	w.next++
+	return v
+}
+
+// configure returns cfg set up to replay w, with the search params and the
+// number of requests and workers of the recording. The assertions of cfg are
+// kept, Config.Validate rejects any other search params given with a replay.
+func (w *workload) configure(cfg Config) Config {
+	assertions := cfg.Assertions
+	cfg.SearchParams = w.params
+	cfg.Assertions = assertions
+	cfg.Total = len(w.requests)
+	cfg.Parallel = w.workers
+	cfg.Nq = len(w.requests[0].vectors)
+	return cfg
+}
+
+// queues returns the requests of every worker in the order they were sent.
+func (w *workload) queues() [][]searchRequest {
+	queues := make([][]searchRequest, w.workers)
+	for _, req := range w.requests {
+		queues[req.worker] = append(queues[req.worker], req)
+	}
+	return queues
+}
+
+// workloadRecorder writes the requests sent by the workers from its own
+// goroutine, the same as traceWriter.
+type workloadRecorder struct {
+	requests chan searchRequest
+	done     chan error
+}
+
+// startCapture starts recording the requests of a run to fname, it returns
+// nil if fname is empty.
+func startCapture(fname string, params SearchParams) (*workloadRecorder, error) {
+	if fname == "" {
+		return nil, nil
+	}
+	f, err := os.Create(fname)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create workload %q", fname)
+	}
+	header, err := json.Marshal(workloadHeader{SearchParams: params})
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	buf := bufio.NewWriter(f)
+	buf.Write(workloadMagic)
+	writeUvarint(buf, uint64(len(header)))
+	buf.Write(header)
+
+	w := &workloadRecorder{
+		requests: make(chan searchRequest, traceBuffer),
+		done:     make(chan error, 1),
+	}
+	go func() {
+		var err error
+		for req := range w.requests {
+			if err == nil {
+				err = writeWorkloadRequest(buf, req)
+			}
+		}
+		if flushErr := buf.Flush(); err == nil {
+			err = flushErr
+		}
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		w.done <- errors.Wrapf(err, "failed to write workload %q", fname)
+	}()
+	return w, nil
+}
+
+// add queues a request sent at offset by worker, it is a no-op on a nil
+// workloadRecorder.
+func (w *workloadRecorder) add(req searchRequest, worker int, offset time.Duration) {
+	if w != nil {
+		req.worker, req.at = worker, offset
+		w.requests <- req
+	}
+}
+
+// Close writes the queued requests and closes the file, it must be called
+// once the workers are done.
+func (w *workloadRecorder) Close() error {
+	if w == nil {
+		return nil
+	}
+	close(w.requests)
+	return <-w.done
+}
+
+func writeUvarint(w *bufio.Writer, v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	w.Write(buf[:binary.PutUvarint(buf[:], v)])
+}
+
+func writeWorkloadRequest(w *bufio.Writer, req searchRequest) error {
+	writeUvarint(w, uint64(req.at))
+	writeUvarint(w, uint64(req.worker))
+	writeUvarint(w, uint64(req.seq))
+	writeUvarint(w, uint64(len(req.vectors)))
+	for _, v := range req.vectors {
+		switch v := v.(type) {
+		case entity.FloatVector:
+			w.WriteByte(workloadFloatVector)
+			writeUvarint(w, uint64(len(v)))
+			var buf [4]byte
+			for _, f := range v {
+				binary.LittleEndian.PutUint32(buf[:], math.Float32bits(f))
+				w.Write(buf[:])
+			}
+		case entity.BinaryVector:
+			w.WriteByte(workloadBinaryVector)
+			writeUvarint(w, uint64(len(v)))
+			w.Write(v)
+		default:
+			return errors.Errorf("unsupported vector type %T", v)
+		}
+	}
+	// the errors of bufio.Writer are sticky, the last write reports them
+	_, err := w.Write(nil)
+	return err
+}
+
+// readWorkload reads a workload written by a run with Config.Capture.
+func readWorkload(fname string) (*workload, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	w, err := decodeWorkload(bufio.NewReader(f))
+	return w, errors.Wrapf(err, "failed to read workload %q", fname)
+}
+
+func decodeWorkload(r *bufio.Reader) (*workload, error) {
+	magic := make([]byte, len(workloadMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != string(workloadMagic) {
+		return nil, errors.New("not a workload file")
+	}
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > maxWorkloadLen {
+		return nil, errors.Errorf("invalid header length %d", n)
+	}
+	header := make([]byte, n)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	var h workloadHeader
+	if err := json.Unmarshal(header, &h); err != nil {
+		return nil, errors.Wrap(err, "invalid header")
+	}
+
+	w := &workload{params: h.SearchParams}
+	for {
+		req, err := readWorkloadRequest(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "request %d", len(w.requests)+1)
+		}
+		if req.worker >= w.workers {
+			w.workers = req.worker + 1
+		}
+		w.requests = append(w.requests, req)
+	}
+	if len(w.requests) == 0 {
+		return nil, errors.New("no requests recorded")
+	}
+	sort.SliceStable(w.requests, func(a, b int) bool {
+		return w.requests[a].at < w.requests[b].at
+	})
+	return w, nil
+}
+
+// readWorkloadRequest returns io.EOF at the end of the file and
+// io.ErrUnexpectedEOF if it ends within a request.
+func readWorkloadRequest(r *bufio.Reader) (searchRequest, error) {
+	var fields [4]uint64
+	for i := range fields {
+		v, err := binary.ReadUvarint(r)
+		if err == io.EOF && i > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return searchRequest{}, err
+		}
+		fields[i] = v
+	}
+	if fields[1] > maxWorkloadLen || fields[3] > maxWorkloadLen {
+		return searchRequest{}, errors.Errorf("invalid worker %d or number of vectors %d", fields[1], fields[3])
+	}
+	req := searchRequest{
+		at:      time.Duration(fields[0]),
+		worker:  int(fields[1]),
+		seq:     int(fields[2]),
+		vectors: make([]entity.Vector, 0, fields[3]),
+	}
+	for i := uint64(0); i < fields[3]; i++ {
+		kind, err := r.ReadByte()
+		if err != nil {
+			return searchRequest{}, io.ErrUnexpectedEOF
+		}
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return searchRequest{}, io.ErrUnexpectedEOF
+		}
+		if n > maxWorkloadLen {
+			return searchRequest{}, errors.Errorf("invalid vector length %d", n)
+		}
+		switch kind {
+		case workloadFloatVector:
+			data := make([]byte, 4*n)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return searchRequest{}, io.ErrUnexpectedEOF
+			}
+			v := make(entity.FloatVector, n)
+			for j := range v {
+				v[j] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*j:]))
+			}
+			req.vectors = append(req.vectors, v)
+		case workloadBinaryVector:
+			v := make(entity.BinaryVector, n)
+			if _, err := io.ReadFull(r, v); err != nil {
+				return searchRequest{}, io.ErrUnexpectedEOF
+			}
+			req.vectors = append(req.vectors, v)
+		default:
+			return searchRequest{}, errors.Errorf("unknown vector kind %d", kind)
+		}
+	}
+	return req, nil
+}
@@ -0,0 +1,109 @@
+package benchmark
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xiaocai2333/milvus-sdk-go/v2/entity"
+)
+
+func writeTestWorkload(t *testing.T, params SearchParams, requests ...searchRequest) string {
+	fname := filepath.Join(t.TempDir(), "workload.bin")
+	w, err := startCapture(fname, params)
+	assert.Nil(t, err)
+	for _, req := range requests {
+		w.add(req, req.worker, req.at)
+	}
+	assert.Nil(t, w.Close())
+	return fname
+}
+
+func TestWorkload(t *testing.T) {
+	params := testConfig("").SearchParams
+	params.Expr = "id > 10"
+	params.PartitionNames = []string{"p1"}
+	requests := []searchRequest{
+		{seq: 1, worker: 1, at: 2 * time.Millisecond, vectors: []entity.Vector{entity.BinaryVector{0xff, 0x01}}},
+		{seq: 0, worker: 0, at: time.Millisecond, vectors: []entity.Vector{entity.FloatVector{1, 2}, entity.FloatVector{-0.5, 3}}},
+		{seq: 2, worker: 0, at: 3 * time.Millisecond, vectors: []entity.Vector{entity.FloatVector{4, 5}}},
+	}
+	fname := writeTestWorkload(t, params, requests...)
+
+	w, err := readWorkload(fname)
+	assert.Nil(t, err)
+	assert.Equal(t, params, w.params)
+	assert.Equal(t, 2, w.workers)
+	assert.Equal(t, []searchRequest{requests[1], requests[0], requests[2]}, w.requests)
+	assert.Equal(t, [][]searchRequest{{requests[1], requests[2]}, {requests[0]}}, w.queues())
+
+	cfg := w.configure(Config{Parallel: 8, Total: 100, SearchParams: SearchParams{Assertions: []string{"p99<1s"}}})
+	assert.Equal(t, 3, cfg.Total)
+	assert.Equal(t, 2, cfg.Parallel)
+	assert.Equal(t, 2, cfg.Nq)
+	assert.Equal(t, "id > 10", cfg.Expr)
+	assert.Equal(t, []string{"p99<1s"}, cfg.Assertions)
+
+	// a file cut within a request is not a shorter workload
+	data, err := os.ReadFile(fname)
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(fname, data[:len(data)-3], 0644))
+	_, err = readWorkload(fname)
+	assert.Error(t, err)
+	_, err = decodeWorkload(bufio.NewReader(strings.NewReader("MBWL")))
+	assert.Error(t, err)
+}
+
+func TestRun_captureReplay(t *testing.T) {
+	f := newFakeMilvus(t, nil)
+	cfg := testConfig(f.addr)
+	cfg.Capture = filepath.Join(t.TempDir(), "workload.bin")
+	_, err := Run(context.Background(), cfg, Queries{{1, 2}})
+	assert.Nil(t, err)
+	assert.Equal(t, cfg.Total, f.count(searchMethod))
+
+	replay := Config{Mode: "locust", Origin: f.addr, Replay: cfg.Capture}
+	r, err := Run(context.Background(), replay, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2*cfg.Total, f.count(searchMethod))
+	assert.Equal(t, cfg.Total, r.Successful)
+	assert.Equal(t, cfg.Parallel, r.Parallelization)
+	assert.Equal(t, cfg.Capture, r.Run.toJSON().Config.Replay)
+	assert.Equal(t, 0.0, *r.Run.toJSON().Config.ReplaySpeed)
+
+	replay.Agents = 1
+	assert.Error(t, replay.Validate())
+	replay.Agents = 0
+	replay.ReplaySpeed = -1
+	assert.Error(t, replay.Validate())
+	replay.ReplaySpeed = 0
+	replay.Assertions = []string{"qps>0"}
+	assert.Nil(t, replay.Validate())
+	replay.ConsistencyLevel = "Strong"
+	assert.Error(t, replay.Validate())
+}
+
+func TestRun_replayPace(t *testing.T) {
+	f := newFakeMilvus(t, nil)
+	vectors := []entity.Vector{entity.FloatVector{1, 2}}
+	fname := writeTestWorkload(t, testConfig("").SearchParams,
+		searchRequest{seq: 0, at: 0, vectors: vectors},
+		searchRequest{seq: 1, at: 100 * time.Millisecond, vectors: vectors},
+		searchRequest{seq: 2, at: 200 * time.Millisecond, vectors: vectors})
+
+	cfg := Config{Mode: "locust", Origin: f.addr, Replay: fname, ReplaySpeed: 1}
+	r, err := Run(context.Background(), cfg, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, r.Successful)
+	assert.True(t, r.Took >= 200*time.Millisecond, r.Took)
+
+	cfg.ReplaySpeed = 4
+	r, err = Run(context.Background(), cfg, nil)
+	assert.Nil(t, err)
+	assert.True(t, r.Took >= 50*time.Millisecond && r.Took < 200*time.Millisecond, r.Took)
+}